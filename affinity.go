@@ -0,0 +1,107 @@
+package lavago
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Which node a guild is assigned to.
+type Affinity struct {
+	// ID of the node, see `Node.ID`.
+	NodeID string
+	// Pinned guilds never move to another node, even if theirs is unavailable.
+	Pinned bool
+}
+
+// Persists guild to node assignments for a `Pool`.
+type AffinityStore interface {
+	// Returns the guild's assignment, false if it has none.
+	Load(guildID string) (Affinity, bool, error)
+	Store(guildID string, a Affinity) error
+	Delete(guildID string) error
+}
+
+// AffinityStore keeping assignments in memory.
+type MemoryAffinityStore struct {
+	m sync.Map // map[string(GuildID)]Affinity
+}
+
+func NewMemoryAffinityStore() *MemoryAffinityStore {
+	return &MemoryAffinityStore{}
+}
+
+func (s *MemoryAffinityStore) Load(guildID string) (Affinity, bool, error) {
+	aI, exists := s.m.Load(guildID)
+	if !exists {
+		return Affinity{}, false, nil
+	}
+	return aI.(Affinity), true, nil
+}
+
+func (s *MemoryAffinityStore) Store(guildID string, a Affinity) error {
+	s.m.Store(guildID, a)
+	return nil
+}
+
+func (s *MemoryAffinityStore) Delete(guildID string) error {
+	s.m.Delete(guildID)
+	return nil
+}
+
+// Returned when a guild is pinned to a node that can't take its player.
+type PinnedNodeUnavailableError struct {
+	GuildID string
+	NodeID  string
+}
+
+func (e *PinnedNodeUnavailableError) Error() string {
+	return fmt.Sprintf("guild %v is pinned to unavailable node %v", e.GuildID, e.NodeID)
+}
+
+// Pins the guild to the node with the given ID so its player is always created there.
+func (pl *Pool) PinGuild(guildID, nodeID string) error {
+	if pl.nodeByID(nodeID) == nil {
+		return fmt.Errorf("no node with ID %v in pool", nodeID)
+	}
+	return pl.Affinity.Store(guildID, Affinity{NodeID: nodeID, Pinned: true})
+}
+
+// Removes the guild's pin, letting it move to other nodes again.
+func (pl *Pool) UnpinGuild(guildID string) error {
+	return pl.Affinity.Delete(guildID)
+}
+
+// Returns the node the guild's player is on or would be created on, nil if no node is available.
+func (pl *Pool) NodeFor(guildID string) (*Node, error) {
+	if n := pl.nodeOf(guildID); n != nil {
+		return n, nil
+	}
+	return pl.assignedNode(guildID, nil)
+}
+
+// Resolves the guild's assignment, falling back to the best node unless the guild is pinned.
+func (pl *Pool) assignedNode(guildID string, exclude map[*Node]bool) (*Node, error) {
+	a, exists, err := pl.Affinity.Load(guildID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		n := pl.nodeByID(a.NodeID)
+		if n != nil && !exclude[n] && n.IsConnected() && !n.Unhealthy() && !n.Overloaded() {
+			return n, nil
+		}
+		if a.Pinned {
+			return nil, &PinnedNodeUnavailableError{GuildID: guildID, NodeID: a.NodeID}
+		}
+	}
+	return pl.bestNode(guildID, exclude), nil
+}
+
+func (pl *Pool) nodeByID(nodeID string) *Node {
+	for _, n := range pl.Nodes() {
+		if n.ID() == nodeID {
+			return n
+		}
+	}
+	return nil
+}
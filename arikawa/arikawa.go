@@ -0,0 +1,125 @@
+// Package arikawa implements lavago's GatewayBridge on top of an arikawa gateway state.
+package arikawa
+
+import (
+	"context"
+	"errors"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/state/store"
+	"github.com/nemphi/lavago"
+)
+
+// Sends voice state updates through arikawa's gateway and relays its voice events.
+type Adapter struct {
+	state  *state.State
+	remove []func()
+}
+
+var (
+	_ lavago.GatewayBridge    = (*Adapter)(nil)
+	_ lavago.VoiceStateLookup = (*Adapter)(nil)
+)
+
+// Creates an adapter for s and, unless node is nil, wires it to node with
+// `lavago.Node.UseGateway`. A nil node is for `lavago.Pool.UseGateway`. Call Close to
+// unregister the handlers it adds.
+func New(node *lavago.Node, s *state.State) *Adapter {
+	a := &Adapter{state: s}
+	if node != nil {
+		node.UseGateway(a)
+	}
+	return a
+}
+
+// Asks Discord to move the bot into the voice channel, or out of voice if channelID is empty.
+func (a *Adapter) SendVoiceStateUpdate(guildID, channelID string, deaf bool) error {
+	gID, err := discord.ParseSnowflake(guildID)
+	if err != nil {
+		return err
+	}
+	cID := discord.NullChannelID
+	if channelID != "" {
+		id, err := discord.ParseSnowflake(channelID)
+		if err != nil {
+			return err
+		}
+		cID = discord.ChannelID(id)
+	}
+	return a.state.SendGateway(context.Background(), &gateway.UpdateVoiceStateCommand{
+		GuildID:   discord.GuildID(gID),
+		ChannelID: cID,
+		SelfDeaf:  deaf,
+	})
+}
+
+// Asks Discord to move the bot into the voice channel.
+func (a *Adapter) ConnectVoice(guildID, channelID string, deaf bool) error {
+	return a.SendVoiceStateUpdate(guildID, channelID, deaf)
+}
+
+// Asks Discord to remove the bot from the guild's voice channel.
+func (a *Adapter) DisconnectVoice(guildID string) error {
+	return a.SendVoiceStateUpdate(guildID, "", false)
+}
+
+// Registers the handler called for every voice state update.
+func (a *Adapter) OnVoiceState(handler func(lavago.VoiceStateUpdate)) {
+	a.remove = append(a.remove, a.state.AddHandler(func(ev *gateway.VoiceStateUpdateEvent) {
+		me, err := a.state.Me()
+		if err != nil {
+			return
+		}
+		channelID := ""
+		if ev.ChannelID.IsValid() {
+			channelID = ev.ChannelID.String()
+		}
+		handler(lavago.VoiceStateUpdate{
+			ShardUserID: me.ID.String(),
+			UserID:      ev.UserID.String(),
+			GuildID:     ev.GuildID.String(),
+			ChannelID:   channelID,
+			SessionID:   ev.SessionID,
+		})
+	}))
+}
+
+// Registers the handler called for every voice server update.
+func (a *Adapter) OnVoiceServer(handler func(lavago.VoiceServerUpdate)) {
+	a.remove = append(a.remove, a.state.AddHandler(func(ev *gateway.VoiceServerUpdateEvent) {
+		handler(lavago.VoiceServerUpdate{GuildID: ev.GuildID.String(), Endpoint: ev.Endpoint, Token: ev.Token})
+	}))
+}
+
+// Voice channel the user is in according to the state's cabinet, empty if they aren't in voice.
+func (a *Adapter) UserVoiceChannel(guildID, userID string) (string, error) {
+	gID, err := discord.ParseSnowflake(guildID)
+	if err != nil {
+		return "", err
+	}
+	uID, err := discord.ParseSnowflake(userID)
+	if err != nil {
+		return "", err
+	}
+	vs, err := a.state.VoiceState(discord.GuildID(gID), discord.UserID(uID))
+	if errors.Is(err, store.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !vs.ChannelID.IsValid() {
+		return "", nil
+	}
+	return vs.ChannelID.String(), nil
+}
+
+// Unregisters the event handlers.
+func (a *Adapter) Close() {
+	for _, rm := range a.remove {
+		rm()
+	}
+	a.remove = nil
+}
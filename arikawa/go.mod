@@ -0,0 +1,16 @@
+module github.com/nemphi/lavago/arikawa
+
+go 1.18
+
+require (
+	github.com/diamondburned/arikawa/v3 v3.4.0
+	github.com/nemphi/lavago v0.0.0
+)
+
+require (
+	github.com/gorilla/schema v1.4.1 // indirect
+	github.com/gorilla/websocket v1.5.3 // indirect
+	golang.org/x/time v0.6.0 // indirect
+)
+
+replace github.com/nemphi/lavago => ../
@@ -0,0 +1,80 @@
+package lavago
+
+import "fmt"
+
+// Player mutation checked by `Player.Authorize`.
+type PlayerAction byte
+
+const (
+	PlayAction PlayerAction = iota
+	SkipAction
+	StopAction
+	PauseAction
+	ResumeAction
+)
+
+func (a PlayerAction) String() string {
+	switch a {
+	case PlayAction:
+		return "Play"
+	case SkipAction:
+		return "Skip"
+	case StopAction:
+		return "Stop"
+	case PauseAction:
+		return "Pause"
+	case ResumeAction:
+		return "Resume"
+	}
+	return fmt.Sprintf("PlayerAction(%d)", byte(a))
+}
+
+func (p *Player) authorize(action PlayerAction, actorID string) error {
+	p.RLock()
+	authorize := p.Authorize
+	p.RUnlock()
+	if authorize == nil {
+		return nil
+	}
+	return authorize(action, actorID)
+}
+
+// Plays the track on behalf of actorID if `Player.Authorize` allows it.
+func (p *Player) PlayAs(actorID string, track *Track) error {
+	if err := p.authorize(PlayAction, actorID); err != nil {
+		return err
+	}
+	return p.PlayTrack(track)
+}
+
+// Skips the current track on behalf of actorID if `Player.Authorize` allows it, see SkipNow.
+func (p *Player) SkipAs(actorID string) (skipped *Track, current *Track, err error) {
+	if err := p.authorize(SkipAction, actorID); err != nil {
+		return nil, nil, err
+	}
+	return p.SkipNow()
+}
+
+// Stops the player on behalf of actorID if `Player.Authorize` allows it.
+func (p *Player) StopAs(actorID string) error {
+	if err := p.authorize(StopAction, actorID); err != nil {
+		return err
+	}
+	return p.Stop()
+}
+
+// Pauses the player on behalf of actorID if `Player.Authorize` allows it.
+func (p *Player) PauseAs(actorID string) error {
+	if err := p.authorize(PauseAction, actorID); err != nil {
+		return err
+	}
+	return p.Pause()
+}
+
+// Resumes the player on behalf of actorID if `Player.Authorize` allows it.
+func (p *Player) ResumeAs(actorID string) error {
+	if err := p.authorize(ResumeAction, actorID); err != nil {
+		return err
+	}
+	return p.Resume()
+}
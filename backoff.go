@@ -0,0 +1,85 @@
+package lavago
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Decides how long the node waits before each reconnect attempt, see `Config.Backoff`.
+type Backoff interface {
+	// Delay before attempt, starting at 1.
+	Delay(attempt int) time.Duration
+}
+
+// Custom backoff.
+type BackoffFunc func(attempt int) time.Duration
+
+func (f BackoffFunc) Delay(attempt int) time.Duration {
+	return f(attempt)
+}
+
+// Waits the same time before every attempt.
+type ConstantBackoff struct {
+	Interval time.Duration
+}
+
+func (b ConstantBackoff) Delay(int) time.Duration {
+	return b.Interval
+}
+
+// Waits Step longer before every attempt, up to Max unless it's zero.
+type LinearBackoff struct {
+	Step time.Duration
+	Max  time.Duration
+}
+
+func (b LinearBackoff) Delay(attempt int) time.Duration {
+	return capDelay(time.Duration(attempt)*b.Step, b.Max)
+}
+
+// Multiplies the delay by Factor after every attempt, starting at Base and up to Max unless
+// it's zero. Jitter between 0 and 1 randomly shortens delays by up to that fraction, so nodes
+// that went down together don't reconnect in lockstep.
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+	// Defaults to 2.
+	Factor float64
+	Jitter float64
+}
+
+func (b ExponentialBackoff) Delay(attempt int) time.Duration {
+	factor := b.Factor
+	if factor <= 0 {
+		factor = 2
+	}
+	// Capped in float64, converting values beyond time.Duration's range overflows.
+	d := float64(b.Base) * math.Pow(factor, float64(attempt-1))
+	if b.Max > 0 && d >= float64(b.Max) {
+		d = float64(b.Max)
+	}
+	delay := time.Duration(math.MaxInt64)
+	if d < float64(math.MaxInt64) {
+		delay = time.Duration(d)
+	}
+	if b.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * math.Min(b.Jitter, 1) * float64(delay))
+	}
+	return delay
+}
+
+func capDelay(d, max time.Duration) time.Duration {
+	if max > 0 && d > max {
+		return max
+	}
+	return d
+}
+
+// Backoff in use, waiting ReconnectDelay longer before every attempt unless Backoff is set.
+func (cfg *Config) backoff() Backoff {
+	if cfg.Backoff != nil {
+		return cfg.Backoff
+	}
+	return LinearBackoff{Step: cfg.ReconnectDelay}
+}
@@ -0,0 +1,120 @@
+package lavago
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// Picks the node a guild's new player is created on.
+type Balancer interface {
+	// Returns one of nodes, which are connected, healthy, not overloaded and in the pool's order. Never empty.
+	Pick(guildID string, nodes []*Node) *Node
+}
+
+// Implemented by balancers mapping guilds onto all of the pool's nodes, so a node being
+// unavailable only moves its own guilds instead of reshuffling everyone's. The pool calls
+// PickStable instead of Pick.
+type StableBalancer interface {
+	Balancer
+	// Like Pick, all holds every node of the pool in its order, available the ones that can be picked.
+	PickStable(guildID string, all, available []*Node) *Node
+}
+
+// Picks the node with the lowest load penalty.
+type LeastLoadedBalancer struct{}
+
+func (LeastLoadedBalancer) Pick(guildID string, nodes []*Node) *Node {
+	best := nodes[0]
+	bestPenalty := best.penalty()
+	for _, n := range nodes[1:] {
+		if penalty := n.penalty(); penalty < bestPenalty {
+			best, bestPenalty = n, penalty
+		}
+	}
+	return best
+}
+
+// Places all guilds of a Discord shard on the same node, so large sharded bots get
+// deterministic placement.
+type ShardBalancer struct {
+	// Total number of shards of the bot.
+	ShardCount int
+}
+
+func (b ShardBalancer) Pick(guildID string, nodes []*Node) *Node {
+	return nodes[ShardID(guildID, b.ShardCount)%len(nodes)]
+}
+
+// Maps the shard onto all nodes, falling back to the available ones only while its node is unavailable.
+func (b ShardBalancer) PickStable(guildID string, all, available []*Node) *Node {
+	n := b.Pick(guildID, all)
+	for _, a := range available {
+		if a == n {
+			return n
+		}
+	}
+	return b.Pick(guildID, available)
+}
+
+// Places guilds on a hash ring of node IDs, so a node going away only moves its own guilds
+// and a node being added only takes over its share. Guilds keep landing on the same node
+// across restarts, which keeps source manager caches warm and avoids mass re-joins.
+type ConsistentHashBalancer struct {
+	// Points per node on the ring, more spread guilds more evenly. Defaults to 100.
+	Replicas int
+}
+
+type ringPoint struct {
+	hash uint64
+	node *Node
+}
+
+func (b ConsistentHashBalancer) Pick(guildID string, nodes []*Node) *Node {
+	replicas := b.Replicas
+	if replicas <= 0 {
+		replicas = 100
+	}
+	ring := make([]ringPoint, 0, len(nodes)*replicas)
+	for _, n := range nodes {
+		id := n.ID()
+		for i := 0; i < replicas; i++ {
+			ring = append(ring, ringPoint{hash: ringHash(id + "#" + strconv.Itoa(i)), node: n})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+	h := ringHash(guildID)
+	i := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= h })
+	if i == len(ring) {
+		i = 0
+	}
+	return ring[i].node
+}
+
+// FNV-1a mixed with splitmix64's finalizer, since FNV alone clusters similar keys like "node#1"
+// and "node#2".
+func ringHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Returns the Discord shard handling the guild. Guild IDs that aren't snowflakes are hashed.
+func ShardID(guildID string, shardCount int) int {
+	if shardCount <= 1 {
+		return 0
+	}
+	id, err := strconv.ParseUint(guildID, 10, 64)
+	if err != nil {
+		h := fnv.New64a()
+		h.Write([]byte(guildID))
+		id = h.Sum64() << 22
+	}
+	return int((id >> 22) % uint64(shardCount))
+}
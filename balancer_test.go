@@ -0,0 +1,33 @@
+package lavago
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestShardBalancerPickStable(t *testing.T) {
+	all := make([]*Node, 3)
+	for i := range all {
+		cfg := NewConfig()
+		cfg.Endpoints = []string{"127.0.0.1:" + strconv.Itoa(i+1)}
+		n, err := NewNode(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all[i] = n
+	}
+	b := ShardBalancer{ShardCount: 3}
+	guildOf := func(shard int) string { return strconv.FormatUint(uint64(shard)<<22, 10) }
+	// The second node is unavailable.
+	available := []*Node{all[0], all[2]}
+	for shard, want := range []*Node{all[0], all[2], all[2]} {
+		if got := b.PickStable(guildOf(shard), all, available); got != want {
+			t.Errorf("shard %d picked node %s, want %s", shard, got.ID(), want.ID())
+		}
+	}
+	for shard := 0; shard < 3; shard++ {
+		if got := b.PickStable(guildOf(shard), all, all); got != all[shard] {
+			t.Errorf("shard %d picked node %s, want %s", shard, got.ID(), all[shard].ID())
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package lavago
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Returned by `Node.Version` for servers older than Lavalink 3.4, which have no /version endpoint.
+var ErrVersionUnknown = errors.New("server doesn't report its version")
+
+// Features the server supports, derived from its version.
+type Capabilities struct {
+	// Version reported by /version, empty for servers older than Lavalink 3.4 and on Lavalink v4,
+	// which isn't asked. See `Node.Version`.
+	Version string
+	// The filters op, since Lavalink 3.4.
+	Filters bool
+	// The /routeplanner endpoints, since Lavalink 3.4 as far as the version tells.
+	RoutePlanner bool
+	// Plugins, since Lavalink 3.5.
+	Plugins bool
+}
+
+// Semantic version like "3.7.11" or "4.0.0-beta.3".
+type SemVer struct {
+	Major, Minor, Patch int
+	// Empty for releases.
+	Prerelease string
+}
+
+// Parses a version, tolerating a leading "v" and a missing patch number. Build metadata is ignored.
+func ParseSemVer(s string) (SemVer, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	v := SemVer{}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		s, v.Prerelease = s[:i], s[i+1:]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return SemVer{}, fmt.Errorf("invalid version %q", s)
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return SemVer{}, fmt.Errorf("invalid version %q", s)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+func (v SemVer) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Returns -1, 0 or 1 if v is older, the same or newer than o. Prereleases are older than their release.
+func (v SemVer) Compare(o SemVer) int {
+	for _, d := range [...]int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	}
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(o.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		na, errA := strconv.Atoi(a[i])
+		nb, errB := strconv.Atoi(b[i])
+		switch {
+		case errA == nil && errB == nil:
+			return sign(na - nb)
+		case errA == nil:
+			// Numeric identifiers sort before alphanumeric ones.
+			return -1
+		case errB == nil:
+			return 1
+		case a[i] < b[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+	return sign(len(a) - len(b))
+}
+
+// Whether v is major.minor or newer, counting prereleases of major.minor.0.
+func (v SemVer) AtLeast(major, minor int) bool {
+	return v.Major > major || v.Major == major && v.Minor >= minor
+}
+
+func sign(d int) int {
+	switch {
+	case d < 0:
+		return -1
+	case d > 0:
+		return 1
+	}
+	return 0
+}
+
+// Fetches the server's version from /version, i.e. "3.7.11". Snapshot builds report a commit hash.
+// Returns ErrVersionUnknown for servers older than Lavalink 3.4.
+func (n *Node) Version(ctx context.Context) (string, error) {
+	res, err := n.requestContext(ctx, "GET", "/version", nil)
+	var se *statusError
+	if errors.As(err, &se) && se.StatusCode == http.StatusNotFound {
+		return "", ErrVersionUnknown
+	}
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 256))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// What the server supports. Lavalink v4 supports everything without asking, older servers are
+// checked once per connection when it's ready. Until a check succeeded, this checks again and
+// returns its error.
+func (n *Node) Capabilities(ctx context.Context) (Capabilities, error) {
+	n.mu.RLock()
+	apiVersion, caps, connects := n.apiVersion, n.caps, n.connects
+	n.mu.RUnlock()
+	if caps != nil {
+		return *caps, nil
+	}
+	if apiVersion >= 4 {
+		return Capabilities{Filters: true, RoutePlanner: true, Plugins: true}, nil
+	}
+	c, err := n.checkCapabilities(ctx)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	n.mu.Lock()
+	// A check made for a previous connection says nothing about the server now.
+	if n.connects == connects {
+		n.caps = &c
+	}
+	n.mu.Unlock()
+	return c, nil
+}
+
+// Checks the capabilities of servers older than Lavalink v4 in the background, so filter
+// updates don't wait for /version.
+func (n *Node) probeCapabilities() {
+	n.mu.RLock()
+	apiVersion := n.apiVersion
+	n.mu.RUnlock()
+	if apiVersion >= 4 {
+		return
+	}
+	ctx, cancel := n.lifecycle()
+	n.socket.spawn(func() {
+		defer cancel()
+		if _, err := n.Capabilities(ctx); err != nil && ctx.Err() == nil {
+			n.logf(LogWarning, "checking capabilities failed: %v", err)
+		}
+	})
+}
+
+func (n *Node) checkCapabilities(ctx context.Context) (Capabilities, error) {
+	version, err := n.Version(ctx)
+	if errors.Is(err, ErrVersionUnknown) {
+		return Capabilities{}, nil
+	}
+	if err != nil {
+		return Capabilities{}, err
+	}
+	c := Capabilities{Version: version}
+	v, err := ParseSemVer(version)
+	if err != nil {
+		// Snapshot builds report a commit hash, they're newer than the endpoint.
+		c.Filters, c.RoutePlanner, c.Plugins = true, true, true
+		return c, nil
+	}
+	c.Filters = v.AtLeast(3, 4)
+	c.RoutePlanner = v.AtLeast(3, 4)
+	c.Plugins = v.AtLeast(3, 5)
+	return c, nil
+}
+
+// Whether the server accepts the filters op, see Capabilities.
+func (n *Node) SupportsFilters(ctx context.Context) (bool, error) {
+	c, err := n.Capabilities(ctx)
+	return c.Filters, err
+}
+
+// Whether the server has the route planner endpoints, see Capabilities.
+func (n *Node) SupportsRoutePlanner(ctx context.Context) (bool, error) {
+	c, err := n.Capabilities(ctx)
+	return c.RoutePlanner, err
+}
+
+// Whether the server can load plugins, see Capabilities.
+func (n *Node) SupportsPlugins(ctx context.Context) (bool, error) {
+	c, err := n.Capabilities(ctx)
+	return c.Plugins, err
+}
@@ -0,0 +1,62 @@
+package lavago
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCapabilitiesRetriedAfterFailure(t *testing.T) {
+	var requests int32
+	n := restNode(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("3.4.0"))
+	})
+	ctx := context.Background()
+	if _, err := n.SupportsFilters(ctx); err == nil {
+		t.Fatal("failed check reported no error")
+	}
+	for i := 0; i < 3; i++ {
+		ok, err := n.SupportsFilters(ctx)
+		if err != nil || !ok {
+			t.Fatalf("got %v, %v, want filters supported", ok, err)
+		}
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Fatalf("made %d requests to /version, want 2", got)
+	}
+}
+
+func TestCapabilitiesCheckedOnConnect(t *testing.T) {
+	f := newFakeLavalink(t)
+	f.version = "3.7.11"
+	n, p := f.player(t)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n.mu.RLock()
+		checked := n.caps != nil
+		n.mu.RUnlock()
+		if checked {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("capabilities weren't checked after connecting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 3; i++ {
+		if err := p.Nightcore(i%2 == 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.versionRequests != 1 {
+		t.Fatalf("made %d requests to /version, want 1", f.versionRequests)
+	}
+}
@@ -0,0 +1,121 @@
+package lavago
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Returned when the current track has no chapters.
+var ErrNoChapters = errors.New("track has no chapters")
+
+// Named section of a track.
+type Chapter struct {
+	Title string
+	Start time.Duration
+	// Zero if the chapter lasts until the next one or the end of the track.
+	End time.Duration
+}
+
+// Chapters as exposed by plugins in the track's pluginInfo, times in milliseconds.
+type chapterJSON struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Start millis `json:"start"`
+	End   millis `json:"end"`
+}
+
+type trackJSON Track
+
+// Reads the chapters plugins list in the track's pluginInfo. Lavalink v4 sends the encoded
+// track as "encoded".
+func (t *Track) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*trackJSON
+		Encoded    string `json:"encoded"`
+		PluginInfo struct {
+			Chapters []chapterJSON `json:"chapters"`
+		} `json:"pluginInfo"`
+	}{trackJSON: (*trackJSON)(t)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.Encoded != "" {
+		t.Track = aux.Encoded
+	}
+	t.Chapters = nil
+	for _, c := range aux.PluginInfo.Chapters {
+		title := c.Name
+		if title == "" {
+			title = c.Title
+		}
+		t.Chapters = append(t.Chapters, Chapter{Title: title, Start: time.Duration(c.Start), End: time.Duration(c.End)})
+	}
+	sort.SliceStable(t.Chapters, func(i, j int) bool {
+		return t.Chapters[i].Start < t.Chapters[j].Start
+	})
+	return nil
+}
+
+// Index of the chapter at position, -1 if the track has no chapters or position is before the first.
+func (t *Track) ChapterAt(position time.Duration) int {
+	return sort.Search(len(t.Chapters), func(i int) bool {
+		return t.Chapters[i].Start > position
+	}) - 1
+}
+
+// Index of the chapter currently playing, -1 if there is none.
+func (p *Player) Chapter() int {
+	p.RLock()
+	defer p.RUnlock()
+	if p.Track == nil {
+		return -1
+	}
+	return p.Track.ChapterAt(p.position())
+}
+
+// Seeks to the start of the current track's i-th chapter.
+func (p *Player) SeekToChapter(i int) error {
+	p.RLock()
+	track := p.Track
+	p.RUnlock()
+	if track == nil {
+		return errors.New("can't seek, no track is playing")
+	}
+	if len(track.Chapters) == 0 {
+		return ErrNoChapters
+	}
+	if i < 0 || i >= len(track.Chapters) {
+		return fmt.Errorf("chapter %v out of range, the track has %v", i, len(track.Chapters))
+	}
+	return p.Seek(track.Chapters[i].Start)
+}
+
+// Seeks to the start of the chapter after the current one.
+func (p *Player) NextChapter() error {
+	return p.SeekToChapter(p.Chapter() + 1)
+}
+
+// Seeks to the start of the current chapter, or the previous one within its first few seconds.
+func (p *Player) PreviousChapter() error {
+	p.RLock()
+	var i int
+	var pos time.Duration
+	if p.Track != nil {
+		pos = p.position()
+		i = p.Track.ChapterAt(pos)
+		if i > 0 && pos-p.Track.Chapters[i].Start < previousChapterGrace {
+			i--
+		}
+	}
+	p.RUnlock()
+	if i < 0 {
+		i = 0
+	}
+	return p.SeekToChapter(i)
+}
+
+// How long after a chapter started PreviousChapter still goes back to the one before it.
+const previousChapterGrace = 3 * time.Second
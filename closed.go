@@ -0,0 +1,173 @@
+package lavago
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Information about the node's own websocket closing, not to be confused with Discord's
+// `WebSocketClosedEvent`.
+type NodeSocketClosedEvent struct {
+	// Node for which this event fired.
+	Node *Node
+	// Websocket close code, 1006 if the connection was lost without one.
+	Code int
+	// Close reason sent by Lavalink, or the network error.
+	Reason string
+	// Whether Lavalink or the network closed the connection rather than Close.
+	ByRemote bool
+	// Retries left if dialing again right away fails, each after a delay from `Config.Backoff`.
+	// Zero if the node won't reconnect.
+	AttemptsLeft int
+}
+
+// Close codes after which connecting again won't help without changes on either side.
+var fatalCloseCodes = map[int]bool{
+	websocket.CloseProtocolError:           true,
+	websocket.CloseUnsupportedData:         true,
+	websocket.CloseInvalidFramePayloadData: true,
+	websocket.ClosePolicyViolation:         true,
+	websocket.CloseMessageTooBig:           true,
+	websocket.CloseMandatoryExtension:      true,
+	websocket.CloseTLSHandshake:            true,
+}
+
+// Whether the node should reconnect. Lost connections, restarts and server errors are
+// retryable, protocol and policy violations are fatal.
+func (e NodeSocketClosedEvent) Retryable() bool {
+	return e.ByRemote && !fatalCloseCodes[e.Code]
+}
+
+// Fired before the node waits to dial Lavalink again after failing to connect.
+type NodeConnectingEvent struct {
+	// Node for which this event fired.
+	Node *Node
+	// Retry about to be made, starting at 1.
+	Attempt int
+	// Retries left after this one.
+	AttemptsLeft int
+	// Wait before dialing, from `Config.Backoff`.
+	Delay time.Duration
+}
+
+// Fired for every failed attempt to connect to Lavalink.
+type NodeConnectFailedEvent struct {
+	// Node for which this event fired.
+	Node *Node
+	Err  error
+	// Address dialed, see `Config.Endpoints`.
+	Address string
+	// Whether the node tries again, on the next address or after a NodeConnectingEvent.
+	WillRetry bool
+}
+
+func (n *Node) socketOnRetry(attempt, left int, delay time.Duration) {
+	n.logf(LogWarning, "reconnecting in %v, attempt %d with %d left", delay, attempt, left)
+	if n.NodeConnecting == nil {
+		return
+	}
+	n.NodeConnecting(NodeConnectingEvent{Node: n, Attempt: attempt, AttemptsLeft: left, Delay: delay})
+}
+
+func (n *Node) socketOnConnectFailed(addr string, err error, retry bool) {
+	n.logf(LogWarning, "connecting to %s failed: %v", addr, err)
+	if n.NodeConnectFailed == nil {
+		return
+	}
+	n.NodeConnectFailed(NodeConnectFailedEvent{Node: n, Err: err, Address: addr, WillRetry: retry})
+}
+
+func (n *Node) socketClosed(e NodeSocketClosedEvent) {
+	n.logf(LogInfo, "websocket closed with code %d: %s", e.Code, e.Reason)
+	if n.NodeSocketClosed == nil {
+		return
+	}
+	n.NodeSocketClosed(e)
+}
+
+// Handles the connection being lost or closed by Lavalink, reconnecting if the close is
+// retryable and `Config.ReconnectAttempts` allows it.
+func (n *Node) socketOnClose(err error) {
+	e := NodeSocketClosedEvent{Node: n, Code: websocket.CloseAbnormalClosure, Reason: err.Error(), ByRemote: true}
+	var ce *websocket.CloseError
+	if errors.As(err, &ce) {
+		e.Code, e.Reason = ce.Code, ce.Text
+	}
+	if e.Retryable() {
+		e.AttemptsLeft = n.config().ReconnectAttempts
+	}
+	n.socketClosed(e)
+	if !e.Retryable() || n.config().ReconnectAttempts <= 0 {
+		n.giveUp()
+		return
+	}
+	if n.config().BufferOnReconnect {
+		n.players.Range(func(_, v interface{}) bool {
+			v.(*Player).startBuffering()
+			return true
+		})
+	}
+	n.reconnect()
+}
+
+// Dials again within the current lifecycle, so players and their goroutines survive.
+func (n *Node) reconnect() {
+	n.holdOps()
+	n.mu.Lock()
+	ctx := n.ctx
+	n.state = NodeStateConnecting
+	n.reconnecting = true
+	n.mu.Unlock()
+	err := n.dial(ctx)
+	if err != nil {
+		n.dropOps()
+		if ctx.Err() == nil {
+			n.socketOnError(err)
+			n.giveUp()
+		}
+		return
+	}
+	n.announce()
+}
+
+// Ends the lifecycle once the node stopped reconnecting, tearing it down like Close so its
+// goroutines stop, Wait returns and Connect can start over. A `Pool` moves the players to
+// other nodes.
+func (n *Node) giveUp() {
+	n.mu.RLock()
+	cancel := n.cancel
+	n.mu.RUnlock()
+	cancel()
+	n.logf(LogError, "gave up reconnecting")
+	n.setState(NodeStateDisconnected)
+	lost := n.snapshotPlayers()
+	n.endLostTracks()
+	n.dropPlayers()
+	n.playersDropped(lost, true)
+}
+
+// Fires a TrackEndedEvent with ConnectionLostReason for every player with a track, since
+// Lavalink won't send one for players it dropped with the session.
+func (n *Node) endLostTracks() {
+	n.players.Range(func(_, v interface{}) bool {
+		p := v.(*Player)
+		switch p.currentState() {
+		case PlayerStatePlaying, PlayerStatePaused, PlayerStateBuffering:
+		default:
+			return true
+		}
+		p.Lock()
+		track := p.Track
+		p.replaced = nil
+		p.pending = nil
+		p.preloaded = nil
+		p.Unlock()
+		p.setState(PlayerStateStopped)
+		if n.TrackEnded != nil && track != nil {
+			n.TrackEnded(TrackEndedEvent{Player: p, Track: track, Reason: ConnectionLostReason})
+		}
+		return true
+	})
+}
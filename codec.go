@@ -0,0 +1,320 @@
+package lavago
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Builds the messages sent for players and parses the track events Lavalink sends. Nodes pick
+// `V3Codec` or `V4Codec` from the API version reported in the handshake unless `Config.Codec`
+// is set, so forks and plugins can extend the wire format.
+type Codec interface {
+	EncodePlay(guildID string, op PlayOp) (Message, error)
+	EncodeStop(guildID string) (Message, error)
+	EncodePause(guildID string, pause bool) (Message, error)
+	EncodeSeek(guildID string, position time.Duration) (Message, error)
+	EncodeVolume(guildID string, volume int) (Message, error)
+	EncodeFilters(guildID string, filters Filters) (Message, error)
+	// Sets the equalizer's bands. current holds the player's other filters, for wire formats
+	// that send every filter at once.
+	EncodeEqualizer(guildID string, bands []EqualizerBand, current Filters) (Message, error)
+	EncodeDestroy(guildID string) (Message, error)
+	EncodeVoice(guildID string, voice RemoteVoiceState) (Message, error)
+	// Parses a message with the "event" op into e, which is zeroed beforehand.
+	DecodeEvent(data []byte, e *EventPayload) error
+}
+
+// Track to play and how, see `Codec.EncodePlay`.
+type PlayOp struct {
+	// Encoded track.
+	Track string
+	// Whether Lavalink ignores the op while a track is playing.
+	NoReplace bool
+	StartTime time.Duration
+	// Zero plays to the end.
+	EndTime time.Duration
+	// Zero keeps the current volume.
+	Volume int
+	Pause  bool
+}
+
+// Message for Lavalink, sent over the websocket unless Method is set.
+type Message struct {
+	// JSON frame, or the request's body. Empty for requests without one.
+	Data []byte
+	// REST method and path, "{sessionId}" in Path is replaced with the node's session ID.
+	Method string
+	Path   string
+}
+
+// Track event with the fields of every event type, see `Codec.DecodeEvent`.
+type EventPayload struct {
+	Op      string `json:"op,omitempty"`
+	GuildID string `json:"guildId,omitempty"`
+	Type    string `json:"type,omitempty"`
+	// Encoded track the event refers to.
+	Track string `json:"track,omitempty"`
+	// Upper case, i.e. "FINISHED".
+	Reason string `json:"reason,omitempty"`
+	// Exception message, same as Exception.Message.
+	Error       string          `json:"error,omitempty"`
+	Exception   *TrackException `json:"exception,omitempty"`
+	ThresholdMs millis          `json:"thresholdMs,omitempty"`
+	Code        int             `json:"code,omitempty"`
+	ByRemote    bool            `json:"byRemote"`
+}
+
+// Fills in whichever of Error and Exception is missing, older servers only send Error.
+func (e *EventPayload) normalizeException() {
+	switch {
+	case e.Exception != nil && e.Error == "":
+		e.Error = e.Exception.Message
+	case e.Exception == nil && e.Error != "":
+		e.Exception = &TrackException{Message: e.Error}
+	}
+}
+
+// Lavalink v3's websocket ops.
+type V3Codec struct{}
+
+func (V3Codec) EncodePlay(guildID string, op PlayOp) (Message, error) {
+	return wsMessage(playerPlayPayload{
+		Op:        "play",
+		GuildID:   guildID,
+		Track:     op.Track,
+		NoReplace: op.NoReplace,
+		StartTime: millis(op.StartTime),
+		EndTime:   millis(op.EndTime),
+		Volume:    op.Volume,
+		Pause:     op.Pause,
+	})
+}
+
+func (V3Codec) EncodeStop(guildID string) (Message, error) {
+	return wsMessage(playerStopPayload{Op: "stop", GuildID: guildID})
+}
+
+func (V3Codec) EncodePause(guildID string, pause bool) (Message, error) {
+	return wsMessage(playerPausePayload{Op: "pause", GuildID: guildID, Pause: pause})
+}
+
+func (V3Codec) EncodeSeek(guildID string, position time.Duration) (Message, error) {
+	return wsMessage(playerSeekPayload{Op: "seek", GuildID: guildID, Position: millis(position)})
+}
+
+func (V3Codec) EncodeVolume(guildID string, volume int) (Message, error) {
+	return wsMessage(playerVolumePayload{Op: "volume", GuildID: guildID, Volume: volume})
+}
+
+func (V3Codec) EncodeFilters(guildID string, filters Filters) (Message, error) {
+	return wsMessage(playerFiltersPayload{Op: "filters", GuildID: guildID, Filters: filters})
+}
+
+func (V3Codec) EncodeEqualizer(guildID string, bands []EqualizerBand, _ Filters) (Message, error) {
+	return wsMessage(playerEqualizerPayload{Op: "equalizer", GuildID: guildID, Bands: bands})
+}
+
+func (V3Codec) EncodeDestroy(guildID string) (Message, error) {
+	return wsMessage(playerDestroyPayload{Op: "destroy", GuildID: guildID})
+}
+
+func (V3Codec) EncodeVoice(guildID string, voice RemoteVoiceState) (Message, error) {
+	return wsMessage(serverUpdatePayload{
+		Op:        "voiceUpdate",
+		GuildID:   guildID,
+		SessionID: voice.SessionID,
+		Event: voiceServerPayload{
+			Token:    voice.Token,
+			Endpoint: voice.Endpoint,
+		},
+	})
+}
+
+func (V3Codec) DecodeEvent(data []byte, e *EventPayload) error {
+	*e = EventPayload{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return err
+	}
+	e.normalizeException()
+	return nil
+}
+
+func wsMessage(v interface{}) (Message, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Data: data}, nil
+}
+
+// Lavalink v4's player update endpoint. Events only differ in carrying track objects.
+type V4Codec struct{}
+
+type v4Track struct {
+	// Nil stops the player.
+	Encoded *string `json:"encoded"`
+}
+
+type v4PlayerUpdate struct {
+	Track    *v4Track          `json:"track,omitempty"`
+	Position *millis           `json:"position,omitempty"`
+	EndTime  *millis           `json:"endTime,omitempty"`
+	Volume   *int              `json:"volume,omitempty"`
+	Paused   *bool             `json:"paused,omitempty"`
+	Filters  *Filters          `json:"filters,omitempty"`
+	Voice    *RemoteVoiceState `json:"voice,omitempty"`
+}
+
+func (V4Codec) EncodePlay(guildID string, op PlayOp) (Message, error) {
+	track := op.Track
+	start := millis(op.StartTime)
+	u := v4PlayerUpdate{
+		Track:    &v4Track{Encoded: &track},
+		Position: &start,
+		Paused:   &op.Pause,
+	}
+	if op.EndTime > 0 {
+		end := millis(op.EndTime)
+		u.EndTime = &end
+	}
+	if op.Volume > 0 {
+		u.Volume = &op.Volume
+	}
+	if op.NoReplace {
+		return v4Update(guildID, "?noReplace=true", u)
+	}
+	return v4Update(guildID, "", u)
+}
+
+func (V4Codec) EncodeStop(guildID string) (Message, error) {
+	return v4Update(guildID, "", v4PlayerUpdate{Track: &v4Track{}})
+}
+
+func (V4Codec) EncodePause(guildID string, pause bool) (Message, error) {
+	return v4Update(guildID, "", v4PlayerUpdate{Paused: &pause})
+}
+
+func (V4Codec) EncodeSeek(guildID string, position time.Duration) (Message, error) {
+	pos := millis(position)
+	return v4Update(guildID, "", v4PlayerUpdate{Position: &pos})
+}
+
+func (V4Codec) EncodeVolume(guildID string, volume int) (Message, error) {
+	return v4Update(guildID, "", v4PlayerUpdate{Volume: &volume})
+}
+
+func (V4Codec) EncodeFilters(guildID string, filters Filters) (Message, error) {
+	return v4Update(guildID, "", v4PlayerUpdate{Filters: &filters})
+}
+
+// Lavalink v4 has no equalizer op, the bands are sent along with the other filters so they
+// stay in place.
+func (V4Codec) EncodeEqualizer(guildID string, bands []EqualizerBand, current Filters) (Message, error) {
+	f := current.clone()
+	f.Equalizer = bands
+	return v4Update(guildID, "", v4PlayerUpdate{Filters: &f})
+}
+
+func (V4Codec) EncodeDestroy(guildID string) (Message, error) {
+	return Message{Method: "DELETE", Path: v4PlayerPath(guildID)}, nil
+}
+
+func (V4Codec) EncodeVoice(guildID string, voice RemoteVoiceState) (Message, error) {
+	return v4Update(guildID, "", v4PlayerUpdate{Voice: &voice})
+}
+
+func (V4Codec) DecodeEvent(data []byte, e *EventPayload) error {
+	*e = EventPayload{}
+	aux := struct {
+		*EventPayload
+		Track json.RawMessage `json:"track,omitempty"`
+	}{EventPayload: e}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if len(aux.Track) > 0 && string(aux.Track) != "null" {
+		track := struct {
+			Encoded string `json:"encoded"`
+		}{}
+		if err := json.Unmarshal(aux.Track, &track); err != nil {
+			return err
+		}
+		e.Track = track.Encoded
+	}
+	e.normalizeException()
+	e.Reason = strings.ToUpper(e.Reason)
+	return nil
+}
+
+func v4PlayerPath(guildID string) string {
+	return "/v4/sessions/{sessionId}/players/" + url.PathEscape(guildID)
+}
+
+func v4Update(guildID, query string, u v4PlayerUpdate) (Message, error) {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Data: data, Method: "PATCH", Path: v4PlayerPath(guildID) + query}, nil
+}
+
+// Codec for the current connection.
+func (n *Node) codec() Codec {
+	if n.config().Codec != nil {
+		return n.config().Codec
+	}
+	if n.APIVersion() >= 4 {
+		return V4Codec{}
+	}
+	return V3Codec{}
+}
+
+// Sends msg over the websocket or, if it's a REST message, as a request for the node's session.
+func (n *Node) send(msg Message) error {
+	if msg.Method == "" {
+		return n.socket.Send(msg.Data)
+	}
+	urlPath := msg.Path
+	if strings.Contains(urlPath, "{sessionId}") {
+		sessionID := n.SessionID()
+		if sessionID == "" {
+			return ErrNoSession
+		}
+		urlPath = strings.ReplaceAll(urlPath, "{sessionId}", sessionID)
+	}
+	var body interface{}
+	if len(msg.Data) > 0 {
+		body = json.RawMessage(msg.Data)
+	}
+	res, err := n.request(msg.Method, urlPath, body)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Codec of the player's node, `V3Codec` for players created without one.
+func (p *Player) codec() Codec {
+	if p.node == nil {
+		return V3Codec{}
+	}
+	return p.node.codec()
+}
+
+// Sends msg through the player's node, or queues it while the node reconnects.
+func (p *Player) send(msg Message) error {
+	p.diag.recordOp(msg)
+	if p.node != nil {
+		if p.node.queueOp(msg) {
+			return nil
+		}
+		return p.node.send(msg)
+	}
+	if msg.Method != "" {
+		return errors.New("can't send REST message, player has no node")
+	}
+	return p.socket.Send(msg.Data)
+}
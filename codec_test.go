@@ -0,0 +1,125 @@
+package lavago
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEncodePlay(t *testing.T) {
+	op := PlayOp{Track: "QAAA", NoReplace: true, StartTime: 1500 * time.Millisecond, Volume: 50}
+	tests := []struct {
+		name   string
+		codec  Codec
+		method string
+		path   string
+		data   string
+	}{
+		{"v3", V3Codec{}, "", "", `{"op":"play","guildId":"1","track":"QAAA","noReplace":true,"startTime":1500,"volume":50,"pause":false}`},
+		{"v4", V4Codec{}, "PATCH", "/v4/sessions/{sessionId}/players/1?noReplace=true", `{"track":{"encoded":"QAAA"},"position":1500,"volume":50,"paused":false}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := tt.codec.EncodePlay("1", op)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Method != tt.method || msg.Path != tt.path {
+				t.Errorf("sent as %q %q, want %q %q", msg.Method, msg.Path, tt.method, tt.path)
+			}
+			if string(msg.Data) != tt.data {
+				t.Errorf("data = %s, want %s", msg.Data, tt.data)
+			}
+		})
+	}
+}
+
+func TestV4EncodeStopAndDestroy(t *testing.T) {
+	msg, err := V4Codec{}.EncodeStop("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"track":{"encoded":null}}`; string(msg.Data) != want {
+		t.Errorf("stop = %s, want %s", msg.Data, want)
+	}
+	msg, err = V4Codec{}.EncodeDestroy("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Method != "DELETE" || msg.Path != "/v4/sessions/{sessionId}/players/1" || msg.Data != nil {
+		t.Errorf("destroy = %q %q %s", msg.Method, msg.Path, msg.Data)
+	}
+}
+
+func TestV4EqualizerKeepsFilters(t *testing.T) {
+	var mu sync.Mutex
+	var sent Filters
+	n := restNode(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var u v4PlayerUpdate
+		if err := json.Unmarshal(body, &u); err != nil || u.Filters == nil {
+			t.Errorf("unexpected update %s", body)
+			return
+		}
+		mu.Lock()
+		sent = *u.Filters
+		mu.Unlock()
+	})
+	n.apiVersion, n.sessionID = 4, "s"
+	p := NewPlayer(nil, "1")
+	p.node = n
+	if err := p.Nightcore(true); err != nil {
+		t.Fatal(err)
+	}
+	bands := []EqualizerBand{{Band: 0, Gain: 0.2}}
+	if err := p.SetBandsV3(bands...); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if sent.Timescale == nil || *sent.Timescale != NightcoreTimescale || len(sent.Equalizer) != 1 || sent.Equalizer[0] != bands[0] {
+		t.Errorf("sent %+v, want the timescale and the equalizer", sent)
+	}
+	if f := p.Filters(); f.Timescale == nil || len(f.Equalizer) != 1 {
+		t.Errorf("player's filters = %+v, want the timescale and the equalizer", f)
+	}
+}
+
+func TestV4DecodeEvent(t *testing.T) {
+	data := []byte(`{"op":"event","type":"TrackExceptionEvent","guildId":"1","track":{"encoded":"QAAA","info":{}},"exception":{"message":"boom","severity":"common"}}`)
+	var e EventPayload
+	if err := (V4Codec{}).DecodeEvent(data, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Track != "QAAA" || e.Error != "boom" || e.GuildID != "1" {
+		t.Errorf("decoded %+v", e)
+	}
+	data = []byte(`{"op":"event","type":"TrackEndEvent","guildId":"1","track":{"encoded":"QAAA"},"reason":"finished"}`)
+	if err := (V4Codec{}).DecodeEvent(data, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Reason != "FINISHED" || e.Error != "" {
+		t.Errorf("decoded %+v, want reason FINISHED and no error", e)
+	}
+}
+
+func TestHandshakeVersion(t *testing.T) {
+	tests := []struct {
+		header http.Header
+		v3Path bool
+		want   int
+	}{
+		{http.Header{"Lavalink-Api-Version": {"3"}}, false, 3},
+		{http.Header{"Lavalink-Major-Version": {"4"}}, false, 4},
+		{http.Header{}, true, 3},
+		{http.Header{}, false, 4},
+	}
+	for _, tt := range tests {
+		if got := handshakeVersion(tt.header, tt.v3Path); got != tt.want {
+			t.Errorf("handshakeVersion(%v, %v) = %v, want %v", tt.header, tt.v3Path, got, tt.want)
+		}
+	}
+}
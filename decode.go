@@ -0,0 +1,118 @@
+package lavago
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fields of a message Lavalink sends, checked by strict decoding.
+type opSchema struct {
+	required []string
+	optional []string
+}
+
+// Top level fields of every op, union of Lavalink v3 and v4. Nested objects aren't checked.
+var opSchemas = map[string]opSchema{
+	"ready": {
+		required: []string{"op", "resumed", "sessionId"},
+	},
+	"stats": {
+		required: []string{"op", "players", "playingPlayers", "uptime", "memory", "cpu"},
+		optional: []string{"frameStats"},
+	},
+	"playerUpdate": {
+		required: []string{"op", "guildId", "state"},
+	},
+	"event": {
+		required: []string{"op", "type", "guildId"},
+		optional: []string{"track", "reason", "error", "exception", "thresholdMs", "code", "byRemote"},
+	},
+}
+
+// Fired for errors the node can't return to a caller, like failed background sends or
+// messages that couldn't be decoded.
+type ErrorEvent struct {
+	// Node for which this event fired.
+	Node *Node
+	// A `*DecodeError` for received messages.
+	Err error
+}
+
+// Returned through `Node.ErrorReceived` when a message from Lavalink couldn't be decoded,
+// or didn't match the expected fields with `Config.StrictDecoding`.
+type DecodeError struct {
+	// Op of the message, empty if it couldn't be read.
+	Op string
+	// Message as received.
+	Payload []byte
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Op == "" {
+		return fmt.Sprintf("can't decode message %s: %v", e.Payload, e.Err)
+	}
+	return fmt.Sprintf("can't decode '%s' message %s: %v", e.Op, e.Payload, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Reports a message that couldn't be decoded. The payload is copied since the socket
+// reuses its buffers.
+func (n *Node) decodeFailed(op string, data []byte, err error) {
+	n.socketOnError(&DecodeError{
+		Op:      op,
+		Payload: append([]byte(nil), data...),
+		Err:     err,
+	})
+}
+
+// Checks data against the schema of its op, failing on unknown ops and on missing or
+// unknown fields.
+func checkStrict(data []byte) (op string, err error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", err
+	}
+	if raw, ok := fields["op"]; ok {
+		if err := json.Unmarshal(raw, &op); err != nil {
+			return "", fmt.Errorf("op isn't a string: %w", err)
+		}
+	}
+	schema, ok := opSchemas[op]
+	if !ok {
+		return op, fmt.Errorf("unknown op %q", op)
+	}
+	var missing, unknown []string
+	for _, f := range schema.required {
+		if _, ok := fields[f]; !ok {
+			missing = append(missing, f)
+		}
+	}
+	for f := range fields {
+		if !contains(schema.required, f) && !contains(schema.optional, f) {
+			unknown = append(unknown, f)
+		}
+	}
+	switch {
+	case len(missing) > 0:
+		return op, fmt.Errorf("missing fields %s", strings.Join(missing, ", "))
+	case len(unknown) > 0:
+		sort.Strings(unknown)
+		return op, fmt.Errorf("unknown fields %s", strings.Join(unknown, ", "))
+	}
+	return op, nil
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package lavago
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// How many entries of each kind a player keeps for `Player.Diagnostics`.
+const diagnosticsSize = 10
+
+// Something that happened to a player, see `Diagnostics`.
+type DiagnosticEntry struct {
+	Time time.Time
+	// Exception message, stuck threshold, close reason or op sent.
+	Message string
+	// Track the entry refers to, nil for voice closes and ops without one.
+	Track *Track
+	// Discord's close code for voice closes.
+	Code int
+}
+
+// Snapshot of a player's recent trouble, oldest entries first. Meant for debug commands
+// showing why audio stopped.
+type Diagnostics struct {
+	GuildID        string
+	State          PlayerState
+	Track          *Track
+	VoiceConnected bool
+	VoicePing      time.Duration
+	// TrackExceptionEvents received.
+	Exceptions []DiagnosticEntry
+	// TrackStuckEvents received.
+	Stuck []DiagnosticEntry
+	// WebSocketClosedEvents of the guild's voice connection.
+	VoiceCloses []DiagnosticEntry
+	// Last messages sent to Lavalink, i.e. "play" or "PATCH /v4/sessions/{sessionId}/players/{guildId}".
+	Ops []DiagnosticEntry
+}
+
+// Most recent exception, stuck track or voice close, nil if there was none.
+func (d *Diagnostics) LastError() *DiagnosticEntry {
+	var last *DiagnosticEntry
+	for _, entries := range [][]DiagnosticEntry{d.Exceptions, d.Stuck, d.VoiceCloses} {
+		if len(entries) == 0 {
+			continue
+		}
+		if e := &entries[len(entries)-1]; last == nil || e.Time.After(last.Time) {
+			last = e
+		}
+	}
+	return last
+}
+
+// Fixed size buffer overwriting its oldest entry.
+type diagnosticRing struct {
+	entries []DiagnosticEntry
+	next    int
+}
+
+func (r *diagnosticRing) add(e DiagnosticEntry) {
+	if len(r.entries) < diagnosticsSize {
+		r.entries = append(r.entries, e)
+		return
+	}
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % diagnosticsSize
+}
+
+// Copy of the entries, oldest first.
+func (r *diagnosticRing) list() []DiagnosticEntry {
+	list := make([]DiagnosticEntry, 0, len(r.entries))
+	list = append(list, r.entries[r.next:]...)
+	return append(list, r.entries[:r.next]...)
+}
+
+// Has its own lock since ops are recorded while the player's is held.
+type playerDiagnostics struct {
+	exceptions  diagnosticRing
+	stuck       diagnosticRing
+	voiceCloses diagnosticRing
+	ops         diagnosticRing
+	sync.Mutex
+}
+
+func (d *playerDiagnostics) record(ring *diagnosticRing, e DiagnosticEntry) {
+	e.Time = time.Now()
+	d.Lock()
+	ring.add(e)
+	d.Unlock()
+}
+
+// Records a message sent to Lavalink by its op or, for REST messages, its method and path.
+func (d *playerDiagnostics) recordOp(msg Message) {
+	op := msg.Method + " " + msg.Path
+	if msg.Method == "" {
+		bp := basePayload{}
+		json.Unmarshal(msg.Data, &bp)
+		op = bp.Op
+	}
+	d.record(&d.ops, DiagnosticEntry{Message: op})
+}
+
+// Recent exceptions, stuck tracks, voice closes and ops sent for the player's guild.
+func (p *Player) Diagnostics() Diagnostics {
+	p.RLock()
+	d := Diagnostics{
+		GuildID:        p.GuildID,
+		State:          p.State,
+		Track:          p.Track,
+		VoiceConnected: p.voiceConnected,
+		VoicePing:      p.voicePing,
+	}
+	p.RUnlock()
+	p.diag.Lock()
+	defer p.diag.Unlock()
+	d.Exceptions = p.diag.exceptions.list()
+	d.Stuck = p.diag.stuck.list()
+	d.VoiceCloses = p.diag.voiceCloses.list()
+	d.Ops = p.diag.ops.list()
+	return d
+}
+
+func (p *Player) recordException(track *Track, message string) {
+	p.diag.record(&p.diag.exceptions, DiagnosticEntry{Message: message, Track: track})
+}
+
+func (p *Player) recordStuck(track *Track, threshold time.Duration) {
+	p.diag.record(&p.diag.stuck, DiagnosticEntry{Message: fmt.Sprintf("stuck for %v", threshold), Track: track})
+}
+
+func (p *Player) recordVoiceClose(code int, reason string) {
+	p.diag.record(&p.diag.voiceCloses, DiagnosticEntry{Message: reason, Code: code})
+}
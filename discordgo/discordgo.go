@@ -0,0 +1,84 @@
+// Package discordgo implements lavago's GatewayBridge on top of a discordgo session.
+//
+//	s, _ := discordgo.New("Bot " + token)
+//	node, _ := lavago.NewNode(lavago.NewConfig())
+//	bridge := lavadiscordgo.New(s)
+//	defer bridge.Close()
+//	node.UseGateway(bridge)
+package discordgo
+
+import (
+	"errors"
+
+	dg "github.com/bwmarrin/discordgo"
+	"github.com/nemphi/lavago"
+)
+
+// Sends voice state updates through a discordgo session and relays its voice events.
+type Bridge struct {
+	session *dg.Session
+	remove  []func()
+}
+
+var (
+	_ lavago.GatewayBridge    = (*Bridge)(nil)
+	_ lavago.VoiceStateLookup = (*Bridge)(nil)
+)
+
+// Creates a bridge for s. Call Close to unregister the handlers it adds.
+func New(s *dg.Session) *Bridge {
+	return &Bridge{session: s}
+}
+
+// Asks Discord to move the bot into the voice channel, or out of voice if channelID is empty.
+func (b *Bridge) SendVoiceStateUpdate(guildID, channelID string, deaf bool) error {
+	return b.session.ChannelVoiceJoinManual(guildID, channelID, false, deaf)
+}
+
+// Voice channel the user is in according to the session's state cache, empty if they
+// aren't in voice. Needs state tracking and the guild voice states intent.
+func (b *Bridge) UserVoiceChannel(guildID, userID string) (string, error) {
+	vs, err := b.session.State.VoiceState(guildID, userID)
+	if errors.Is(err, dg.ErrStateNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return vs.ChannelID, nil
+}
+
+// Registers the handler called for every voice state update.
+func (b *Bridge) OnVoiceState(handler func(lavago.VoiceStateUpdate)) {
+	b.remove = append(b.remove, b.session.AddHandler(func(s *dg.Session, ev *dg.VoiceStateUpdate) {
+		if s.State == nil || s.State.User == nil {
+			return
+		}
+		handler(lavago.VoiceStateUpdate{
+			ShardUserID: s.State.User.ID,
+			UserID:      ev.UserID,
+			GuildID:     ev.GuildID,
+			ChannelID:   ev.ChannelID,
+			SessionID:   ev.SessionID,
+		})
+	}))
+}
+
+// Registers the handler called for every voice server update.
+func (b *Bridge) OnVoiceServer(handler func(lavago.VoiceServerUpdate)) {
+	b.remove = append(b.remove, b.session.AddHandler(func(s *dg.Session, ev *dg.VoiceServerUpdate) {
+		handler(lavago.VoiceServerUpdate{
+			GuildID:  ev.GuildID,
+			Endpoint: ev.Endpoint,
+			Token:    ev.Token,
+		})
+	}))
+}
+
+// Unregisters the handlers.
+func (b *Bridge) Close() {
+	for _, rm := range b.remove {
+		rm()
+	}
+	b.remove = nil
+}
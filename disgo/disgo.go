@@ -0,0 +1,152 @@
+// Package disgo implements lavago's GatewayBridge on top of a disgo bot client.
+//
+// A minimal bot forwards voice events and joins through the adapter:
+//
+//	client, _ := disgo.New(token,
+//		bot.WithGatewayConfigOpts(gateway.WithIntents(gateway.IntentGuilds, gateway.IntentGuildVoiceStates)),
+//		bot.WithCacheConfigOpts(cache.WithCaches(cache.FlagVoiceStates)),
+//	)
+//	node, _ := lavago.NewNode(lavago.NewConfig())
+//	a := lavadisgo.New(node, client)
+//	defer a.Close()
+//	_ = client.OpenGateway(ctx)
+//	_ = node.Connect(client.ID().String(), "1")
+//	player, err := a.Join(guildID, channelID)
+//
+// Pools use the adapter as their bridge instead, with a nil node:
+//
+//	pool.UseGateway(lavadisgo.New(nil, client))
+package disgo
+
+import (
+	"context"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/nemphi/lavago"
+)
+
+// Sends voice state updates through disgo's gateway and relays its voice events.
+type Adapter struct {
+	node      *lavago.Node
+	client    bot.Client
+	listeners []bot.EventListener
+}
+
+var (
+	_ lavago.GatewayBridge    = (*Adapter)(nil)
+	_ lavago.VoiceStateLookup = (*Adapter)(nil)
+)
+
+// Creates an adapter for client and, unless node is nil, wires it to node with
+// `lavago.Node.UseGateway`. Join, Leave and Player need a node. Call Close to remove the
+// listeners it adds.
+func New(node *lavago.Node, client bot.Client) *Adapter {
+	a := &Adapter{node: node, client: client}
+	if node != nil {
+		node.UseGateway(a)
+	}
+	return a
+}
+
+// Asks Discord to move the bot into the voice channel, or out of voice if channelID is empty.
+func (a *Adapter) SendVoiceStateUpdate(guildID, channelID string, deaf bool) error {
+	gID, err := snowflake.Parse(guildID)
+	if err != nil {
+		return err
+	}
+	var cID *snowflake.ID
+	if channelID != "" {
+		id, err := snowflake.Parse(channelID)
+		if err != nil {
+			return err
+		}
+		cID = &id
+	}
+	return a.client.UpdateVoiceState(context.Background(), gID, cID, false, deaf)
+}
+
+// Asks Discord to move the bot into the voice channel.
+func (a *Adapter) ConnectVoice(guildID, channelID string, deaf bool) error {
+	return a.SendVoiceStateUpdate(guildID, channelID, deaf)
+}
+
+// Asks Discord to remove the bot from the guild's voice channel.
+func (a *Adapter) DisconnectVoice(guildID string) error {
+	return a.SendVoiceStateUpdate(guildID, "", false)
+}
+
+// Registers the handler called for every voice state update.
+func (a *Adapter) OnVoiceState(handler func(lavago.VoiceStateUpdate)) {
+	a.listen(bot.NewListenerFunc(func(ev *events.GuildVoiceStateUpdate) {
+		vs := ev.VoiceState
+		channelID := ""
+		if vs.ChannelID != nil {
+			channelID = vs.ChannelID.String()
+		}
+		handler(lavago.VoiceStateUpdate{
+			ShardUserID: a.client.ID().String(),
+			UserID:      vs.UserID.String(),
+			GuildID:     vs.GuildID.String(),
+			ChannelID:   channelID,
+			SessionID:   vs.SessionID,
+		})
+	}))
+}
+
+// Registers the handler called for every voice server update.
+func (a *Adapter) OnVoiceServer(handler func(lavago.VoiceServerUpdate)) {
+	a.listen(bot.NewListenerFunc(func(ev *events.VoiceServerUpdate) {
+		u := lavago.VoiceServerUpdate{GuildID: ev.GuildID.String(), Token: ev.Token}
+		// Discord sends a null endpoint while the voice server is being reallocated.
+		if ev.Endpoint != nil {
+			u.Endpoint = *ev.Endpoint
+		}
+		handler(u)
+	}))
+}
+
+func (a *Adapter) listen(l bot.EventListener) {
+	a.listeners = append(a.listeners, l)
+	a.client.AddEventListeners(l)
+}
+
+// Voice channel the user is in according to client's cache, empty if they aren't in voice.
+// Needs the cache.FlagVoiceStates cache flag.
+func (a *Adapter) UserVoiceChannel(guildID, userID string) (string, error) {
+	gID, err := snowflake.Parse(guildID)
+	if err != nil {
+		return "", err
+	}
+	uID, err := snowflake.Parse(userID)
+	if err != nil {
+		return "", err
+	}
+	vs, ok := a.client.Caches().VoiceState(gID, uID)
+	if !ok || vs.ChannelID == nil {
+		return "", nil
+	}
+	return vs.ChannelID.String(), nil
+}
+
+// Joins the voice channel and returns the guild's player.
+func (a *Adapter) Join(guildID, channelID snowflake.ID) (*lavago.Player, error) {
+	return a.node.Join(guildID.String(), channelID.String())
+}
+
+// Destroys the guild's player and leaves its voice channel.
+func (a *Adapter) Leave(guildID snowflake.ID) error {
+	return a.node.Leave(guildID.String())
+}
+
+// Returns the guild's player, nil if it has none.
+func (a *Adapter) Player(guildID snowflake.ID) *lavago.Player {
+	return a.node.GetPlayer(guildID.String())
+}
+
+// Removes the event listeners.
+func (a *Adapter) Close() {
+	a.client.RemoveEventListeners(a.listeners...)
+	a.listeners = nil
+}
@@ -0,0 +1,137 @@
+package disgo
+
+import (
+	"testing"
+
+	"github.com/disgoorg/disgo"
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/cache"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/disgo/gateway"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/nemphi/lavago"
+)
+
+const (
+	testGuild   = snowflake.ID(817327181659111454)
+	testChannel = snowflake.ID(817327181659111455)
+	testUser    = snowflake.ID(817327181659111456)
+	testBot     = snowflake.ID(123456789012345678)
+)
+
+func testClient(t *testing.T) *Adapter {
+	t.Helper()
+	client, err := disgo.New("MTIzNDU2Nzg5MDEyMzQ1Njc4.x.y", bot.WithCacheConfigOpts(cache.WithCaches(cache.FlagVoiceStates)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Set by the ready event otherwise.
+	client.Caches().SetSelfUser(discord.OAuth2User{User: discord.User{ID: testBot}})
+	node, err := lavago.NewNode(lavago.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(node, client)
+}
+
+func TestUseGateway(t *testing.T) {
+	a := testClient(t)
+	defer a.Close()
+	if a.node.ConnectVoice == nil || a.node.DisconnectVoice == nil || a.node.UserVoiceChannel == nil {
+		t.Fatal("node not wired to the adapter")
+	}
+	// OnVoiceState and OnVoiceServer.
+	if len(a.listeners) != 2 {
+		t.Fatalf("%d listeners, want 2", len(a.listeners))
+	}
+	a.Close()
+	if a.listeners != nil {
+		t.Error("listeners kept after Close")
+	}
+}
+
+func TestUserVoiceChannel(t *testing.T) {
+	a := testClient(t)
+	defer a.Close()
+	channelID := testChannel
+	a.client.Caches().AddVoiceState(discord.VoiceState{GuildID: testGuild, UserID: testUser, ChannelID: &channelID})
+	a.client.Caches().AddVoiceState(discord.VoiceState{GuildID: testGuild, UserID: testUser + 1})
+
+	tests := []struct {
+		name    string
+		userID  string
+		want    string
+		wantErr bool
+	}{
+		{"in voice", testUser.String(), testChannel.String(), false},
+		{"left voice", (testUser + 1).String(), "", false},
+		{"not cached", (testUser + 2).String(), "", false},
+		{"invalid", "user", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.node.UserVoiceChannel(testGuild.String(), tt.userID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("channel = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVoiceEvents(t *testing.T) {
+	a := testClient(t)
+	defer a.Close()
+	var states []lavago.VoiceStateUpdate
+	var servers []lavago.VoiceServerUpdate
+	a.OnVoiceState(func(u lavago.VoiceStateUpdate) { states = append(states, u) })
+	a.OnVoiceServer(func(u lavago.VoiceServerUpdate) { servers = append(servers, u) })
+
+	channelID := testChannel
+	em := a.client.EventManager()
+	em.DispatchEvent(&events.GuildVoiceStateUpdate{GenericGuildVoiceState: &events.GenericGuildVoiceState{
+		GenericEvent: events.NewGenericEvent(a.client, 0, 0),
+		VoiceState:   discord.VoiceState{GuildID: testGuild, UserID: testUser, ChannelID: &channelID, SessionID: "session"},
+	}})
+	em.DispatchEvent(&events.GuildVoiceStateUpdate{GenericGuildVoiceState: &events.GenericGuildVoiceState{
+		GenericEvent: events.NewGenericEvent(a.client, 0, 0),
+		VoiceState:   discord.VoiceState{GuildID: testGuild, UserID: testUser, SessionID: "session"},
+	}})
+	endpoint := "us-east1.discord.media:443"
+	em.DispatchEvent(&events.VoiceServerUpdate{
+		GenericEvent:           events.NewGenericEvent(a.client, 0, 0),
+		EventVoiceServerUpdate: gateway.EventVoiceServerUpdate{GuildID: testGuild, Token: "token", Endpoint: &endpoint},
+	})
+	em.DispatchEvent(&events.VoiceServerUpdate{
+		GenericEvent:           events.NewGenericEvent(a.client, 0, 0),
+		EventVoiceServerUpdate: gateway.EventVoiceServerUpdate{GuildID: testGuild, Token: "token"},
+	})
+
+	wantStates := []lavago.VoiceStateUpdate{
+		{ShardUserID: testBot.String(), UserID: testUser.String(), GuildID: testGuild.String(), ChannelID: testChannel.String(), SessionID: "session"},
+		{ShardUserID: testBot.String(), UserID: testUser.String(), GuildID: testGuild.String(), SessionID: "session"},
+	}
+	if len(states) != len(wantStates) {
+		t.Fatalf("%d voice states, want %d", len(states), len(wantStates))
+	}
+	for i, want := range wantStates {
+		if states[i] != want {
+			t.Errorf("voice state %d = %+v, want %+v", i, states[i], want)
+		}
+	}
+	wantServers := []lavago.VoiceServerUpdate{
+		{GuildID: testGuild.String(), Endpoint: endpoint, Token: "token"},
+		{GuildID: testGuild.String(), Token: "token"},
+	}
+	if len(servers) != len(wantServers) {
+		t.Fatalf("%d voice servers, want %d", len(servers), len(wantServers))
+	}
+	for i, want := range wantServers {
+		if servers[i] != want {
+			t.Errorf("voice server %d = %+v, want %+v", i, servers[i], want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package disgo_test
+
+import (
+	"context"
+
+	"github.com/disgoorg/disgo"
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/cache"
+	"github.com/disgoorg/disgo/gateway"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/nemphi/lavago"
+	lavadisgo "github.com/nemphi/lavago/disgo"
+)
+
+// A minimal bot playing a track in the voice channel it's asked to join.
+func Example() {
+	ctx := context.Background()
+	client, err := disgo.New("token",
+		bot.WithGatewayConfigOpts(gateway.WithIntents(gateway.IntentGuilds, gateway.IntentGuildVoiceStates)),
+		// PlayQuery looks up the requester's channel in the voice state cache.
+		bot.WithCacheConfigOpts(cache.WithCaches(cache.FlagVoiceStates)),
+	)
+	if err != nil {
+		panic(err)
+	}
+	node, err := lavago.NewNode(lavago.NewConfig())
+	if err != nil {
+		panic(err)
+	}
+	a := lavadisgo.New(node, client)
+	defer a.Close()
+	if err := client.OpenGateway(ctx); err != nil {
+		panic(err)
+	}
+	if err := node.Connect(client.ID().String(), "1"); err != nil {
+		panic(err)
+	}
+
+	// Joins the requester's voice channel, found through the client's voice state cache.
+	guildID, requesterID := snowflake.ID(817327181659111454), snowflake.ID(817327181659111455)
+	res, err := node.Guild(guildID.String()).PlayQuery(ctx, "ytsearch:never gonna give you up", requesterID.String())
+	if err != nil {
+		panic(err)
+	}
+	_ = res.Outcome
+}
@@ -0,0 +1,48 @@
+package lavago
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// How severe Lavalink considers an exception.
+type Severity string
+
+const (
+	// Caused by the track itself, i.e. an unavailable or age restricted video.
+	SeverityCommon Severity = "COMMON"
+	// Cause unknown, possibly a bug in a source.
+	SeveritySuspicious Severity = "SUSPICIOUS"
+	// Caused by the server, i.e. it's being rate limited or a source broke.
+	SeverityFault Severity = "FAULT"
+)
+
+// Lavalink v3 sends severities in upper case and v4 in lower case.
+func (s *Severity) UnmarshalJSON(data []byte) error {
+	var v string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = Severity(strings.ToUpper(v))
+	return nil
+}
+
+// Whether the server is at fault rather than the track, so retrying elsewhere may help.
+func (s Severity) IsFault() bool {
+	return s == SeverityFault
+}
+
+// Exception a track threw while playing.
+type TrackException struct {
+	Message  string   `json:"message"`
+	Severity Severity `json:"severity"`
+	// Underlying exception, i.e. "java.net.SocketTimeoutException: Read timed out".
+	Cause string `json:"cause,omitempty"`
+}
+
+func (e *TrackException) Error() string {
+	if e.Cause == "" {
+		return e.Message
+	}
+	return e.Message + ": " + e.Cause
+}
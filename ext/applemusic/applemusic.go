@@ -0,0 +1,429 @@
+// Package applemusic plays Apple Music links by reading their metadata and mirroring every
+// track to YouTube Music. Metadata comes from Apple Music's catalog API when a developer token
+// is set, and otherwise from the public iTunes lookup API and the page's meta tags.
+//
+//	client := applemusic.New("")
+//	err := client.ResolveFunc(ctx, node, link, func(tracks []*lavago.Track) {
+//		for _, t := range tracks {
+//			player.Queue.Enqueue(t)
+//		}
+//	})
+package applemusic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nemphi/lavago"
+)
+
+const (
+	catalogURL = "https://api.music.apple.com"
+	lookupURL  = "https://itunes.apple.com/lookup"
+)
+
+// Defaults used when the matching Client field isn't set.
+const (
+	DefaultStorefront  = "us"
+	DefaultConcurrency = 4
+	DefaultBatchSize   = 25
+)
+
+// The iTunes lookup API accepts this many IDs per request.
+const maxLookupIDs = 150
+
+// Returned when a link isn't an Apple Music song, album or playlist.
+var ErrUnsupportedLink = errors.New("not an apple music song, album or playlist link")
+
+// Returned by Resolve when none of the link's tracks could be mirrored.
+var ErrNoTracks = errors.New("no apple music track could be mirrored")
+
+// Kind of Apple Music link.
+type Kind string
+
+const (
+	KindSong     Kind = "song"
+	KindAlbum    Kind = "album"
+	KindPlaylist Kind = "playlist"
+)
+
+// Apple Music's metadata of a track, attached as UserData to the mirrored `lavago.Track`.
+type Metadata struct {
+	ID     string
+	Title  string
+	Artist string
+	Album  string
+	// Only known when using the catalog API.
+	ISRC     string
+	Duration time.Duration
+	// Link to the song on Apple Music.
+	URL     string
+	Artwork string
+}
+
+// Reads Apple Music metadata.
+type Client struct {
+	// Apple Music API developer token. Without it playlists are limited to the songs their page lists.
+	DeveloperToken string
+	// Storefront used for the catalog API and lookups when the link has none. Defaults to `DefaultStorefront`.
+	Storefront string
+	// Used for Apple's APIs. Defaults to `http.DefaultClient`.
+	HTTPClient *http.Client
+	// How many tracks are mirrored at once. Defaults to `DefaultConcurrency`.
+	Concurrency int
+	// How many mirrored tracks ResolveFunc hands over at once. Defaults to `DefaultBatchSize`.
+	BatchSize int
+}
+
+// Creates a client, developerToken may be empty.
+func New(developerToken string) *Client {
+	return &Client{
+		DeveloperToken: developerToken,
+		Storefront:     DefaultStorefront,
+		Concurrency:    DefaultConcurrency,
+		BatchSize:      DefaultBatchSize,
+	}
+}
+
+// Whether link is an Apple Music link Resolve handles.
+func IsLink(link string) bool {
+	_, _, _, err := Parse(link)
+	return err == nil
+}
+
+// Same as IsLink, so the client can be used as a resolver.LinkResolver.
+func (c *Client) Match(link string) bool {
+	return IsLink(link)
+}
+
+// Returns the kind, storefront and ID of a link like "https://music.apple.com/us/album/name/123?i=456",
+// which refers to the song 456 of album 123. The storefront is empty if the link has none.
+func Parse(link string) (kind Kind, storefront, id string, err error) {
+	u, err := url.Parse(link)
+	if err != nil || (u.Host != "music.apple.com" && u.Host != "geo.music.apple.com") {
+		return "", "", "", ErrUnsupportedLink
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) > 0 && len(parts[0]) == 2 {
+		storefront, parts = parts[0], parts[1:]
+	}
+	if len(parts) < 2 {
+		return "", "", "", ErrUnsupportedLink
+	}
+	kind, id = Kind(parts[0]), parts[len(parts)-1]
+	switch kind {
+	case KindAlbum:
+		if song := u.Query().Get("i"); song != "" {
+			return KindSong, storefront, song, nil
+		}
+	case KindSong, KindPlaylist:
+	default:
+		return "", "", "", ErrUnsupportedLink
+	}
+	return kind, storefront, id, nil
+}
+
+// Fetches the metadata of every track behind the link, in order.
+func (c *Client) Metadata(ctx context.Context, link string) ([]*Metadata, error) {
+	kind, storefront, id, err := Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	if storefront == "" {
+		storefront = c.Storefront
+	}
+	if storefront == "" {
+		storefront = DefaultStorefront
+	}
+	if c.DeveloperToken != "" {
+		return c.catalog(ctx, kind, storefront, id)
+	}
+	switch kind {
+	case KindSong:
+		return c.lookup(ctx, storefront, []string{id}, false)
+	case KindAlbum:
+		return c.lookup(ctx, storefront, []string{id}, true)
+	default:
+		ids, err := c.pageSongs(ctx, link)
+		if err != nil {
+			return nil, err
+		}
+		return c.lookup(ctx, storefront, ids, false)
+	}
+}
+
+// Like ResolveFunc, but returns every mirrored track at once.
+func (c *Client) Resolve(ctx context.Context, node *lavago.Node, link string) ([]*lavago.Track, error) {
+	var tracks []*lavago.Track
+	err := c.ResolveFunc(ctx, node, link, func(batch []*lavago.Track) {
+		tracks = append(tracks, batch...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tracks, nil
+}
+
+// Fetches the link's metadata and mirrors each track through `lavago.Node.Mirror`, calling fn
+// in order with every BatchSize mirrored tracks so large playlists can start playing early.
+// Tracks get their Metadata as UserData, the ones without a match are left out. Fails if none
+// matched, and with the node's error as soon as a batch fails because it couldn't be reached.
+func (c *Client) ResolveFunc(ctx context.Context, node *lavago.Node, link string, fn func([]*lavago.Track)) error {
+	metas, err := c.Metadata(ctx, link)
+	if err != nil {
+		return err
+	}
+	concurrency, batchSize := c.Concurrency, c.BatchSize
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+	found := false
+	for start := 0; start < len(metas); start += batchSize {
+		end := start + batchSize
+		if end > len(metas) {
+			end = len(metas)
+		}
+		wants := make([]*lavago.Track, end-start)
+		for i, meta := range metas[start:end] {
+			wants[i] = &lavago.Track{Info: meta.trackInfo(), UserData: meta}
+		}
+		batch, err := node.MirrorMany(ctx, wants, concurrency)
+		if errors.Is(err, lavago.ErrNoMirror) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		fn(batch)
+	}
+	if !found {
+		return ErrNoTracks
+	}
+	return nil
+}
+
+func (m *Metadata) trackInfo() lavago.TrackInfo {
+	return lavago.TrackInfo{
+		Identifier: m.ID,
+		Title:      m.Title,
+		Author:     m.Artist,
+		Length:     m.Duration,
+		URL:        m.URL,
+		SourceName: "applemusic",
+		Artwork:    m.Artwork,
+		ISRC:       m.ISRC,
+	}
+}
+
+type catalogResource struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Attributes struct {
+		Name             string `json:"name"`
+		ArtistName       string `json:"artistName"`
+		AlbumName        string `json:"albumName"`
+		ISRC             string `json:"isrc"`
+		DurationInMillis int64  `json:"durationInMillis"`
+		URL              string `json:"url"`
+		Artwork          struct {
+			URL string `json:"url"`
+		} `json:"artwork"`
+	} `json:"attributes"`
+	Relationships struct {
+		Tracks catalogPage `json:"tracks"`
+	} `json:"relationships"`
+}
+
+type catalogPage struct {
+	Data []catalogResource `json:"data"`
+	Next string            `json:"next"`
+}
+
+func (r *catalogResource) metadata() *Metadata {
+	a := r.Attributes
+	return &Metadata{
+		ID:       r.ID,
+		Title:    a.Name,
+		Artist:   a.ArtistName,
+		Album:    a.AlbumName,
+		ISRC:     a.ISRC,
+		Duration: time.Duration(a.DurationInMillis) * time.Millisecond,
+		URL:      a.URL,
+		Artwork:  strings.NewReplacer("{w}", "600", "{h}", "600").Replace(a.Artwork.URL),
+	}
+}
+
+func (c *Client) catalog(ctx context.Context, kind Kind, storefront, id string) ([]*Metadata, error) {
+	page := catalogPage{}
+	err := c.catalogGet(ctx, fmt.Sprintf("/v1/catalog/%s/%ss/%s", storefront, kind, url.PathEscape(id)), &page)
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Data) == 0 {
+		return nil, ErrUnsupportedLink
+	}
+	if kind == KindSong {
+		return []*Metadata{page.Data[0].metadata()}, nil
+	}
+	var metas []*Metadata
+	tracks := page.Data[0].Relationships.Tracks
+	for {
+		for i := range tracks.Data {
+			if tracks.Data[i].Type == "songs" {
+				metas = append(metas, tracks.Data[i].metadata())
+			}
+		}
+		if tracks.Next == "" {
+			return metas, nil
+		}
+		next := tracks.Next
+		tracks = catalogPage{}
+		if err := c.catalogGet(ctx, next, &tracks); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) catalogGet(ctx context.Context, urlPath string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", catalogURL+urlPath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.DeveloperToken)
+	return c.do(req, v)
+}
+
+type lookupResult struct {
+	WrapperType     string `json:"wrapperType"`
+	TrackID         int64  `json:"trackId"`
+	TrackName       string `json:"trackName"`
+	ArtistName      string `json:"artistName"`
+	CollectionName  string `json:"collectionName"`
+	TrackTimeMillis int64  `json:"trackTimeMillis"`
+	TrackViewURL    string `json:"trackViewUrl"`
+	ArtworkURL100   string `json:"artworkUrl100"`
+}
+
+// Looks songs up by ID with the iTunes API, or the songs of albums when albums is set.
+// Songs come back in the order of ids.
+func (c *Client) lookup(ctx context.Context, storefront string, ids []string, albums bool) ([]*Metadata, error) {
+	var metas []*Metadata
+	found := map[string]*Metadata{}
+	for start := 0; start < len(ids); start += maxLookupIDs {
+		end := start + maxLookupIDs
+		if end > len(ids) {
+			end = len(ids)
+		}
+		q := url.Values{
+			"id":      {strings.Join(ids[start:end], ",")},
+			"country": {storefront},
+		}
+		if albums {
+			q.Set("entity", "song")
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", lookupURL+"?"+q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		res := struct {
+			Results []lookupResult `json:"results"`
+		}{}
+		if err := c.do(req, &res); err != nil {
+			return nil, err
+		}
+		for _, r := range res.Results {
+			if r.WrapperType != "track" {
+				continue
+			}
+			m := &Metadata{
+				ID:       fmt.Sprint(r.TrackID),
+				Title:    r.TrackName,
+				Artist:   r.ArtistName,
+				Album:    r.CollectionName,
+				Duration: time.Duration(r.TrackTimeMillis) * time.Millisecond,
+				URL:      r.TrackViewURL,
+				Artwork:  strings.Replace(r.ArtworkURL100, "100x100", "600x600", 1),
+			}
+			if albums {
+				metas = append(metas, m)
+			} else {
+				found[m.ID] = m
+			}
+		}
+	}
+	// The API doesn't keep the order of the requested songs.
+	for _, id := range ids {
+		if m := found[id]; m != nil && !albums {
+			metas = append(metas, m)
+		}
+	}
+	if len(metas) == 0 {
+		return nil, ErrNoTracks
+	}
+	return metas, nil
+}
+
+var songMetaTag = regexp.MustCompile(`<meta[^>]+property="music:song"[^>]+content="([^"]+)"`)
+
+// Returns the IDs of the songs a playlist page lists in its meta tags.
+func (c *Client) pageSongs(ctx context.Context, link string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("apple music responded with %v", res.Status)
+	}
+	page, err := io.ReadAll(io.LimitReader(res.Body, 8<<20))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, m := range songMetaTag.FindAllSubmatch(page, -1) {
+		kind, _, id, err := Parse(html.UnescapeString(string(m[1])))
+		if err == nil && kind == KindSong {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, ErrNoTracks
+	}
+	return ids, nil
+}
+
+func (c *Client) client() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) do(req *http.Request, v interface{}) error {
+	res, err := c.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("apple music responded with %v", res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
@@ -0,0 +1,73 @@
+// Package chapters derives chapters from video descriptions listing timestamps, like most
+// YouTube descriptions do, for tracks whose plugin info has none.
+//
+//	track.Chapters = chapters.FromDescription(description, track.Info.Length)
+//	err := player.NextChapter()
+package chapters
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nemphi/lavago"
+)
+
+// Timestamp like "1:02" or "1:02:03" at the start of a line, optionally bracketed,
+// or at its end after the title.
+var (
+	leading  = regexp.MustCompile(`^[\[(]?((?:\d{1,2}:)?\d{1,2}:\d{2})[\])]?\s*[-–—:|.]?\s*(.*)$`)
+	trailing = regexp.MustCompile(`^(.*?)\s*[-–—:|]?\s*[\[(]?((?:\d{1,2}:)?\d{1,2}:\d{2})[\])]?$`)
+)
+
+// Parses timestamped lines of description into chapters. Like YouTube, it requires at least
+// two timestamps, the first at 0:00, in increasing order, otherwise nil is returned. Each
+// chapter ends where the next starts, the last at length unless it's zero.
+func FromDescription(description string, length time.Duration) []lavago.Chapter {
+	var chapters []lavago.Chapter
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimSpace(line)
+		var stamp, title string
+		if m := leading.FindStringSubmatch(line); m != nil {
+			stamp, title = m[1], m[2]
+		} else if m := trailing.FindStringSubmatch(line); m != nil {
+			stamp, title = m[2], m[1]
+		} else {
+			continue
+		}
+		start, ok := parseTimestamp(stamp)
+		if !ok || length > 0 && start >= length {
+			continue
+		}
+		chapters = append(chapters, lavago.Chapter{Title: strings.TrimSpace(title), Start: start})
+	}
+	if len(chapters) < 2 || chapters[0].Start != 0 {
+		return nil
+	}
+	if !sort.SliceIsSorted(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start }) {
+		return nil
+	}
+	for i := range chapters {
+		if i+1 < len(chapters) {
+			chapters[i].End = chapters[i+1].Start
+		} else {
+			chapters[i].End = length
+		}
+	}
+	return chapters
+}
+
+func parseTimestamp(stamp string) (time.Duration, bool) {
+	var d time.Duration
+	parts := strings.Split(stamp, ":")
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || i > 0 && v >= 60 {
+			return 0, false
+		}
+		d = d*60 + time.Duration(v)
+	}
+	return d * time.Second, true
+}
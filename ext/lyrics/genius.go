@@ -0,0 +1,141 @@
+package lyrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/nemphi/lavago"
+)
+
+const geniusAPIURL = "https://api.genius.com"
+
+// Finds plain lyrics on Genius. The API only returns the song's page, the lyrics are read from it.
+type Genius struct {
+	// Client access token from https://genius.com/api-clients.
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// Creates a provider using the given client access token.
+func NewGenius(apiKey string) *Genius {
+	return &Genius{APIKey: apiKey}
+}
+
+// Takes the first search hit whose artist matches, then scrapes its page.
+func (p *Genius) Lyrics(ctx context.Context, track *lavago.Track) (*Lyrics, error) {
+	artist, title := query(track)
+	req, err := http.NewRequestWithContext(ctx, "GET", geniusAPIURL+"/search?"+url.Values{"q": {artist + " " + title}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	res := struct {
+		Response struct {
+			Hits []struct {
+				Type   string `json:"type"`
+				Result struct {
+					URL           string `json:"url"`
+					PrimaryArtist struct {
+						Name string `json:"name"`
+					} `json:"primary_artist"`
+				} `json:"result"`
+			} `json:"hits"`
+		} `json:"response"`
+	}{}
+	body, err := p.do(req)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		return nil, err
+	}
+	page := ""
+	for _, hit := range res.Response.Hits {
+		if hit.Type == "song" && strings.EqualFold(hit.Result.PrimaryArtist.Name, artist) {
+			page = hit.Result.URL
+			break
+		}
+	}
+	if page == "" {
+		return nil, ErrNotFound
+	}
+	req, err = http.NewRequestWithContext(ctx, "GET", page, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err = p.do(req)
+	if err != nil {
+		return nil, err
+	}
+	text := scrapeLyrics(body)
+	if text == "" {
+		return nil, ErrNotFound
+	}
+	return &Lyrics{Provider: "genius", Text: text, SourceURL: page}, nil
+}
+
+func (p *Genius) do(req *http.Request) ([]byte, error) {
+	hc := p.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("genius responded with %v", res.Status)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, 8<<20))
+}
+
+var (
+	lyricsContainer = []byte(`data-lyrics-container="true"`)
+	divTag          = regexp.MustCompile(`(?i)<(/?)div\b[^>]*>`)
+	lineBreak       = regexp.MustCompile(`(?i)<br\s*/?>`)
+	anyTag          = regexp.MustCompile(`<[^>]+>`)
+)
+
+// Extracts the text of every lyrics container of a Genius song page.
+func scrapeLyrics(page []byte) string {
+	var parts []string
+	for {
+		i := bytes.Index(page, lyricsContainer)
+		if i < 0 {
+			break
+		}
+		page = page[i:]
+		start := bytes.IndexByte(page, '>')
+		if start < 0 {
+			break
+		}
+		page = page[start+1:]
+		// Find the container's closing tag, skipping nested divs.
+		end, depth := len(page), 1
+		for _, m := range divTag.FindAllSubmatchIndex(page, -1) {
+			if m[3] > m[2] {
+				depth--
+			} else {
+				depth++
+			}
+			if depth == 0 {
+				end = m[0]
+				break
+			}
+		}
+		part := lineBreak.ReplaceAllString(string(page[:end]), "\n")
+		parts = append(parts, html.UnescapeString(anyTag.ReplaceAllString(part, "")))
+		page = page[end:]
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
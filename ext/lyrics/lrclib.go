@@ -0,0 +1,109 @@
+package lyrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/nemphi/lavago"
+)
+
+// LRCLIB instance used when LRCLIB.BaseURL isn't set.
+const DefaultLRCLIBURL = "https://lrclib.net"
+
+// Finds plain and time-synced lyrics on LRCLIB, which needs no API key.
+type LRCLIB struct {
+	// Defaults to `DefaultLRCLIBURL`.
+	BaseURL string
+	// LRCLIB asks clients to identify themselves.
+	UserAgent  string
+	HTTPClient *http.Client
+}
+
+// Creates a provider for the public LRCLIB instance.
+func NewLRCLIB() *LRCLIB {
+	return &LRCLIB{
+		BaseURL:   DefaultLRCLIBURL,
+		UserAgent: "Lavago/" + lavago.Version,
+	}
+}
+
+type lrclibRecord struct {
+	ID           int64   `json:"id"`
+	Instrumental bool    `json:"instrumental"`
+	PlainLyrics  string  `json:"plainLyrics"`
+	SyncedLyrics string  `json:"syncedLyrics"`
+	Duration     float64 `json:"duration"`
+}
+
+// Looks the track up by artist, title and length, falling back to a search when there's no exact match.
+func (p *LRCLIB) Lyrics(ctx context.Context, track *lavago.Track) (*Lyrics, error) {
+	artist, title := query(track)
+	q := url.Values{"track_name": {title}, "artist_name": {artist}}
+	if track.Info.Length > 0 && !track.Info.IsStream {
+		q.Set("duration", strconv.Itoa(int(track.Info.Length.Seconds())))
+	}
+	rec := lrclibRecord{}
+	found, err := p.get(ctx, "/api/get", q, &rec)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		q.Del("duration")
+		var recs []lrclibRecord
+		_, err = p.get(ctx, "/api/search", q, &recs)
+		if err != nil {
+			return nil, err
+		}
+		if len(recs) == 0 {
+			return nil, ErrNotFound
+		}
+		rec = recs[0]
+	}
+	if rec.Instrumental || rec.PlainLyrics == "" && rec.SyncedLyrics == "" {
+		return nil, ErrNotFound
+	}
+	return &Lyrics{
+		Provider:  "lrclib",
+		Text:      rec.PlainLyrics,
+		Lines:     ParseLRC(rec.SyncedLyrics),
+		SourceURL: fmt.Sprintf("%s/api/get/%d", p.baseURL(), rec.ID),
+	}, nil
+}
+
+func (p *LRCLIB) baseURL() string {
+	if p.BaseURL != "" {
+		return p.BaseURL
+	}
+	return DefaultLRCLIBURL
+}
+
+// Decodes the response into v, returning false without an error when LRCLIB has no match.
+func (p *LRCLIB) get(ctx context.Context, urlPath string, q url.Values, v interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL()+urlPath+"?"+q.Encode(), nil)
+	if err != nil {
+		return false, err
+	}
+	if p.UserAgent != "" {
+		req.Header.Set("User-Agent", p.UserAgent)
+	}
+	hc := p.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("lrclib responded with %v", res.Status)
+	}
+	return true, json.NewDecoder(res.Body).Decode(v)
+}
@@ -0,0 +1,217 @@
+// Package lyrics fetches track lyrics from the server's LavaLyrics plugin, or from external
+// providers for servers without it.
+//
+//	chain := lyrics.Chain{lyrics.NewPlugin(node), lyrics.NewLRCLIB(), lyrics.NewGenius(apiKey)}
+//	provider := lyrics.NewCache(chain, 256)
+//	np := player.NowPlaying()
+//	l, err := provider.Lyrics(ctx, np.Track)
+//	line := l.LineAt(np.Position)
+package lyrics
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nemphi/lavago"
+)
+
+// Returned when a provider has no lyrics for a track.
+var ErrNotFound = errors.New("lyrics not found")
+
+// Lyrics of a track.
+type Lyrics struct {
+	// Name of the provider that found them, i.e. "lrclib".
+	Provider string
+	// Plain lyrics, lines separated by "\n".
+	Text string
+	// Time-synced lines in order, nil if the provider has none.
+	Lines []Line
+	// Page the lyrics came from, if any.
+	SourceURL string
+}
+
+// Line of time-synced lyrics.
+type Line struct {
+	// Position in the track the line starts at.
+	Start time.Duration
+	Text  string
+}
+
+// Whether the lyrics have time-synced lines.
+func (l *Lyrics) Synced() bool {
+	return len(l.Lines) > 0
+}
+
+// Index of the synced line sung at position, -1 if the first line didn't start yet or the lyrics aren't synced.
+func (l *Lyrics) LineAt(position time.Duration) int {
+	return sort.Search(len(l.Lines), func(i int) bool {
+		return l.Lines[i].Start > position
+	}) - 1
+}
+
+// Finds lyrics for tracks.
+type LyricsProvider interface {
+	// Returns ErrNotFound if the provider has no lyrics for the track.
+	Lyrics(ctx context.Context, track *lavago.Track) (*Lyrics, error)
+}
+
+// Asks each provider in order until one has lyrics. Providers failing for other reasons are
+// skipped too, the first such error is returned if none had lyrics.
+type Chain []LyricsProvider
+
+func (c Chain) Lyrics(ctx context.Context, track *lavago.Track) (*Lyrics, error) {
+	var firstErr error
+	for _, p := range c {
+		l, err := p.Lyrics(ctx, track)
+		if err == nil {
+			return l, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if firstErr == nil && !errors.Is(err, ErrNotFound) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, ErrNotFound
+}
+
+// Remembers a provider's answers, including tracks without lyrics, for the most recently used tracks.
+type Cache struct {
+	Provider LyricsProvider
+	// How long missing lyrics are remembered. Defaults to an hour.
+	NotFoundTTL time.Duration
+	// Tracks remembered at most, unlimited if zero.
+	Size int
+
+	entries map[string]*list.Element
+	order   *list.List
+	mu      sync.Mutex
+}
+
+type cacheEntry struct {
+	key     string
+	lyrics  *Lyrics
+	expires time.Time
+}
+
+// Creates a cache holding up to size tracks.
+func NewCache(provider LyricsProvider, size int) *Cache {
+	return &Cache{
+		Provider:    provider,
+		NotFoundTTL: time.Hour,
+		Size:        size,
+	}
+}
+
+func (c *Cache) Lyrics(ctx context.Context, track *lavago.Track) (*Lyrics, error) {
+	key := cacheKey(track)
+	c.mu.Lock()
+	c.init()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		if e.expires.IsZero() || time.Now().Before(e.expires) {
+			c.order.MoveToFront(el)
+			c.mu.Unlock()
+			if e.lyrics == nil {
+				return nil, ErrNotFound
+			}
+			return e.lyrics, nil
+		}
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+
+	l, err := c.Provider.Lyrics(ctx, track)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	e := &cacheEntry{key: key, lyrics: l}
+	if l == nil {
+		ttl := c.NotFoundTTL
+		if ttl <= 0 {
+			ttl = time.Hour
+		}
+		e.expires = time.Now().Add(ttl)
+	}
+	c.mu.Lock()
+	c.init()
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+	}
+	c.entries[key] = c.order.PushFront(e)
+	for c.Size > 0 && c.order.Len() > c.Size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+	c.mu.Unlock()
+	return l, err
+}
+
+// Creates the maps of caches not made with NewCache. Called with mu held.
+func (c *Cache) init() {
+	if c.entries == nil {
+		c.entries = map[string]*list.Element{}
+		c.order = list.New()
+	}
+}
+
+func cacheKey(t *lavago.Track) string {
+	if t.Info.Identifier != "" {
+		return t.Info.SourceName + ":" + t.Info.Identifier
+	}
+	return t.Info.Author + "\x00" + t.Info.Title
+}
+
+var (
+	bracketed   = regexp.MustCompile(`\s*[(\[][^)\]]*[)\]]`)
+	topicOrVEVO = regexp.MustCompile(`(?i)\s*(- topic|vevo)$`)
+)
+
+// Artist and title to look a track up with. Drops suffixes like "(Official Video)" and
+// splits YouTube titles like "Artist - Title".
+func query(t *lavago.Track) (artist, title string) {
+	artist = strings.TrimSpace(topicOrVEVO.ReplaceAllString(t.Info.Author, ""))
+	title = strings.TrimSpace(bracketed.ReplaceAllString(t.Info.Title, ""))
+	if i := strings.Index(title, " - "); i > 0 {
+		artist, title = strings.TrimSpace(title[:i]), strings.TrimSpace(title[i+3:])
+	}
+	return artist, title
+}
+
+var lrcTimestamp = regexp.MustCompile(`\[(\d+):(\d+(?:\.\d+)?)\]`)
+
+// Parses LRC formatted lyrics like "[01:02.50] line" into lines sorted by start.
+// Lines with several timestamps are repeated, lines without are skipped.
+func ParseLRC(lrc string) []Line {
+	var lines []Line
+	for _, raw := range strings.Split(lrc, "\n") {
+		stamps := lrcTimestamp.FindAllStringSubmatchIndex(raw, -1)
+		if len(stamps) == 0 {
+			continue
+		}
+		text := strings.TrimSpace(raw[stamps[len(stamps)-1][1]:])
+		for _, s := range stamps {
+			min, _ := strconv.Atoi(raw[s[2]:s[3]])
+			sec, _ := strconv.ParseFloat(raw[s[4]:s[5]], 64)
+			start := time.Duration(min)*time.Minute + time.Duration(sec*float64(time.Second))
+			lines = append(lines, Line{Start: start, Text: text})
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Start < lines[j].Start
+	})
+	return lines
+}
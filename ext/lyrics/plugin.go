@@ -0,0 +1,78 @@
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/nemphi/lavago"
+)
+
+// Gets lyrics from the node's LavaLyrics plugin. Reports ErrNotFound when the server doesn't
+// have the plugin, so it goes first in a Chain of external providers that fill in for it.
+type Plugin struct {
+	Node *lavago.Node
+
+	// Whether the server has the plugin, nil until it was asked.
+	available *bool
+	mu        sync.Mutex
+}
+
+// Creates a provider asking node's plugin.
+func NewPlugin(node *lavago.Node) *Plugin {
+	return &Plugin{Node: node}
+}
+
+// Whether the server has the plugin. Asked through `lavago.Node.Info` once the node is
+// connected, failed checks are retried on the next call.
+func (p *Plugin) Available(ctx context.Context) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.available != nil {
+		return *p.available, nil
+	}
+	if !p.Node.IsConnected() {
+		return false, errors.New("can't check for the lyrics plugin, node isn't connected")
+	}
+	available := false
+	// The plugin needs Lavalink v4.
+	if p.Node.APIVersion() >= 4 {
+		info, err := p.Node.Info(ctx)
+		if err != nil {
+			return false, err
+		}
+		available = info.HasPlugin(lavago.LyricsPluginName)
+	}
+	p.available = &available
+	return available, nil
+}
+
+func (p *Plugin) Lyrics(ctx context.Context, track *lavago.Track) (*Lyrics, error) {
+	available, err := p.Available(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !available || track.Track == "" {
+		return nil, ErrNotFound
+	}
+	pl, err := p.Node.TrackLyrics(ctx, track.Track)
+	if errors.Is(err, lavago.ErrNoLyrics) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	l := &Lyrics{Provider: pl.SourceName, Text: pl.Text}
+	for _, line := range pl.Lines {
+		l.Lines = append(l.Lines, Line{Start: line.Start, Text: line.Text})
+	}
+	if l.Text == "" && len(l.Lines) > 0 {
+		texts := make([]string, len(l.Lines))
+		for i, line := range l.Lines {
+			texts[i] = line.Text
+		}
+		l.Text = strings.Join(texts, "\n")
+	}
+	return l, nil
+}
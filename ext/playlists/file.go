@@ -0,0 +1,114 @@
+package playlists
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// PlaylistStore keeping each owner's playlists in a JSON file inside a directory.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Creates a store in dir, creating the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) Get(owner, name string) (*Playlist, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	playlists, err := s.load(owner)
+	if err != nil {
+		return nil, err
+	}
+	pl, exists := playlists[name]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return pl, nil
+}
+
+func (s *FileStore) List(owner string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	playlists, err := s.load(owner)
+	if err != nil {
+		return nil, err
+	}
+	return sortedNames(playlists), nil
+}
+
+func (s *FileStore) Save(pl *Playlist) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	playlists, err := s.load(pl.Owner)
+	if err != nil {
+		return err
+	}
+	playlists[pl.Name] = stamp(pl, playlists[pl.Name])
+	return s.store(pl.Owner, playlists)
+}
+
+func (s *FileStore) Delete(owner, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	playlists, err := s.load(owner)
+	if err != nil {
+		return err
+	}
+	if _, exists := playlists[name]; !exists {
+		return ErrNotFound
+	}
+	delete(playlists, name)
+	return s.store(owner, playlists)
+}
+
+func (s *FileStore) path(owner string) string {
+	return filepath.Join(s.dir, url.PathEscape(owner)+".json")
+}
+
+func (s *FileStore) load(owner string) (map[string]*Playlist, error) {
+	playlists := map[string]*Playlist{}
+	data, err := os.ReadFile(s.path(owner))
+	if errors.Is(err, fs.ErrNotExist) {
+		return playlists, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &playlists); err != nil {
+		return nil, err
+	}
+	return playlists, nil
+}
+
+// Writes the owner's playlists to a temporary file first so a crash never leaves a partial file.
+func (s *FileStore) store(owner string, playlists map[string]*Playlist) error {
+	data, err := json.Marshal(playlists)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".playlists-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(owner))
+}
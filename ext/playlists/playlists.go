@@ -0,0 +1,156 @@
+// Package playlists stores named playlists per guild or user and loads them into players.
+package playlists
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nemphi/lavago"
+)
+
+// Returned when a playlist doesn't exist.
+var ErrNotFound = errors.New("playlist not found")
+
+// Named list of tracks owned by a guild or user.
+type Playlist struct {
+	// Guild or user ID the playlist belongs to.
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	// Encoded tracks in play order.
+	Tracks    []string  `json:"tracks"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Keeps playlists. Names are unique per owner.
+type PlaylistStore interface {
+	// Returns the playlist or ErrNotFound.
+	Get(owner, name string) (*Playlist, error)
+	// Names of the owner's playlists, sorted.
+	List(owner string) ([]string, error)
+	// Creates the playlist or replaces an existing one with the same owner and name.
+	Save(pl *Playlist) error
+	// Removes the playlist, returning ErrNotFound if it doesn't exist.
+	Delete(owner, name string) error
+}
+
+// Creates a playlist from the tracks currently in the queue.
+func FromQueue(owner, name string, q *lavago.TrackQueue) *Playlist {
+	tracks := q.Values()
+	pl := &Playlist{Owner: owner, Name: name, Tracks: make([]string, len(tracks))}
+	for i, t := range tracks {
+		pl.Tracks[i] = lavago.EncodeTrack(t)
+	}
+	return pl
+}
+
+// Decodes the playlist's tracks locally, setting their requester.
+func (pl *Playlist) Decode(requester string) ([]*lavago.Track, error) {
+	tracks := make([]*lavago.Track, len(pl.Tracks))
+	for i, encoded := range pl.Tracks {
+		t, err := lavago.DecodeTrackString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("track %v of playlist %q: %w", i, pl.Name, err)
+		}
+		t.Requester = requester
+		tracks[i] = t
+	}
+	return tracks, nil
+}
+
+// Loads the owner's playlist and enqueues its tracks on the player's queue on behalf of
+// requester, stopping at the first track the queue's policy rejects. Returns how many tracks
+// were enqueued.
+func EnqueuePlaylist(p *lavago.Player, store PlaylistStore, owner, name, requester string) (int, error) {
+	pl, err := store.Get(owner, name)
+	if err != nil {
+		return 0, err
+	}
+	tracks, err := pl.Decode(requester)
+	if err != nil {
+		return 0, err
+	}
+	for i, t := range tracks {
+		if err := p.Queue.Enqueue(t); err != nil {
+			return i, err
+		}
+	}
+	return len(tracks), nil
+}
+
+// PlaylistStore keeping playlists in memory.
+type MemoryStore struct {
+	owners map[string]map[string]*Playlist
+	mu     sync.RWMutex
+}
+
+// Creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{owners: map[string]map[string]*Playlist{}}
+}
+
+func (s *MemoryStore) Get(owner, name string) (*Playlist, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pl, exists := s.owners[owner][name]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return pl.clone(), nil
+}
+
+func (s *MemoryStore) List(owner string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedNames(s.owners[owner]), nil
+}
+
+func (s *MemoryStore) Save(pl *Playlist) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owners[pl.Owner] == nil {
+		s.owners[pl.Owner] = map[string]*Playlist{}
+	}
+	s.owners[pl.Owner][pl.Name] = stamp(pl, s.owners[pl.Owner][pl.Name])
+	return nil
+}
+
+func (s *MemoryStore) Delete(owner, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.owners[owner][name]; !exists {
+		return ErrNotFound
+	}
+	delete(s.owners[owner], name)
+	return nil
+}
+
+func (pl *Playlist) clone() *Playlist {
+	c := *pl
+	c.Tracks = append([]string(nil), pl.Tracks...)
+	return &c
+}
+
+// Copy of pl with its timestamps set, keeping the creation time of the playlist it replaces.
+func stamp(pl, replaced *Playlist) *Playlist {
+	c := pl.clone()
+	now := time.Now()
+	c.CreatedAt = now
+	if replaced != nil {
+		c.CreatedAt = replaced.CreatedAt
+	}
+	c.UpdatedAt = now
+	return c
+}
+
+func sortedNames(playlists map[string]*Playlist) []string {
+	names := make([]string, 0, len(playlists))
+	for name := range playlists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,157 @@
+// Package radio finds internet radio stations with the radio-browser.info directory.
+// Stations are plain audio streams Lavalink plays with a direct load.
+//
+//	stations, err := radio.New().SearchStations(ctx, "jazz", 10)
+//	track, err := stations[0].Load(node)
+package radio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/nemphi/lavago"
+)
+
+// Public radio-browser.info mirror used when Client.BaseURL isn't set.
+const DefaultBaseURL = "https://de1.api.radio-browser.info"
+
+// Returned by `Station.Load` when the stream couldn't be loaded.
+var ErrUnplayable = errors.New("station stream couldn't be loaded")
+
+// Radio station listed by radio-browser.info.
+type Station struct {
+	UUID string `json:"stationuuid"`
+	Name string `json:"name"`
+	// Stream url as submitted, possibly a playlist file.
+	URL string `json:"url"`
+	// Stream url with playlists resolved, preferred for playback.
+	ResolvedURL string `json:"url_resolved"`
+	Homepage    string `json:"homepage"`
+	Favicon     string `json:"favicon"`
+	// Comma separated, i.e. "jazz,smooth jazz".
+	Tags string `json:"tags"`
+	// ISO 3166-1 alpha-2 code, i.e. "DE".
+	CountryCode string `json:"countrycode"`
+	Language    string `json:"language"`
+	Codec       string `json:"codec"`
+	// Kbps, zero if unknown.
+	Bitrate    int `json:"bitrate"`
+	Votes      int `json:"votes"`
+	ClickCount int `json:"clickcount"`
+}
+
+// Client for the radio-browser.info API.
+type Client struct {
+	// Defaults to `DefaultBaseURL`.
+	BaseURL string
+	// Sent with every request, radio-browser.info asks clients to identify themselves.
+	UserAgent string
+	// Defaults to `http.DefaultClient`.
+	HTTPClient *http.Client
+}
+
+// Creates a client for the default mirror.
+func New() *Client {
+	return &Client{
+		BaseURL:   DefaultBaseURL,
+		UserAgent: "Lavago/" + lavago.Version,
+	}
+}
+
+// Searches stations by name, most popular first. Broken streams are left out.
+func (c *Client) SearchStations(ctx context.Context, name string, limit int) ([]*Station, error) {
+	if name == "" {
+		return nil, errors.New("can't search stations with empty name")
+	}
+	q := popular(limit)
+	q.Set("name", name)
+	return c.stations(ctx, "/json/stations/search", q)
+}
+
+// Most popular stations of a country, given as an ISO 3166-1 alpha-2 code like "US".
+// Broken streams are left out.
+func (c *Client) TopStations(ctx context.Context, country string, limit int) ([]*Station, error) {
+	if len(country) != 2 {
+		return nil, errors.New("country must be a two letter code")
+	}
+	return c.stations(ctx, "/json/stations/bycountrycodeexact/"+url.PathEscape(strings.ToUpper(country)), popular(limit))
+}
+
+// Query parameters listing working stations by popularity.
+func popular(limit int) url.Values {
+	q := url.Values{
+		"hidebroken": {"true"},
+		"order":      {"clickcount"},
+		"reverse":    {"true"},
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	return q
+}
+
+func (c *Client) stations(ctx context.Context, urlPath string, q url.Values) ([]*Station, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", base+urlPath+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("radio-browser responded with %v", res.Status)
+	}
+	var stations []*Station
+	err = json.NewDecoder(res.Body).Decode(&stations)
+	if err != nil {
+		return nil, err
+	}
+	return stations, nil
+}
+
+// Url Lavalink should load, the resolved stream when known.
+func (s *Station) StreamURL() string {
+	if s.ResolvedURL != "" {
+		return s.ResolvedURL
+	}
+	return s.URL
+}
+
+// Loads the station's stream as a track, titled with the station's name and keeping the
+// Station as UserData.
+func (s *Station) Load(node *lavago.Node) (*lavago.Track, error) {
+	sr, err := node.Search(lavago.Direct, s.StreamURL())
+	if err != nil {
+		return nil, err
+	}
+	if sr.Status != lavago.TrackLoadedSearchStatus || len(sr.Tracks) == 0 {
+		return nil, ErrUnplayable
+	}
+	t := sr.Tracks[0]
+	// Streams often report a generic or empty title.
+	t.Info.Title = s.Name
+	if t.Info.Artwork == "" {
+		t.Info.Artwork = s.Favicon
+	}
+	t.UserData = s
+	return t, nil
+}
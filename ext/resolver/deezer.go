@@ -0,0 +1,271 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nemphi/lavago"
+)
+
+const deezerAPIURL = "https://api.deezer.com"
+
+// Returned when a link isn't a Deezer track, album or playlist.
+var ErrUnsupportedDeezerLink = errors.New("not a deezer track, album or playlist link")
+
+// Deezer's metadata of a track, attached as UserData to mirrored tracks.
+type DeezerTrack struct {
+	ID       int64
+	Title    string
+	Artist   string
+	Album    string
+	ISRC     string
+	Duration time.Duration
+	// Link to the track on Deezer.
+	URL     string
+	Artwork string
+}
+
+// Resolves Deezer links. When the node has LavaSrc's Deezer source, tracks are loaded by ISRC with
+// "dzisrc:" and searched with "dzsearch:" otherwise, while albums and playlists are left to LavaSrc.
+// Without it every track is mirrored through `lavago.Node.Mirror`, using Deezer's public API for metadata.
+type Deezer struct {
+	// Used for Deezer's API. Defaults to `http.DefaultClient`.
+	HTTPClient *http.Client
+	// How many tracks are mirrored at once. Defaults to 4.
+	Concurrency int
+
+	// Whether each node has the deezer source, checked once per node.
+	sources sync.Map
+}
+
+// Creates a Deezer resolver.
+func NewDeezer() *Deezer {
+	return &Deezer{Concurrency: 4}
+}
+
+// Whether link is a Deezer track, album or playlist link like "https://www.deezer.com/en/track/3135556".
+func (d *Deezer) Match(link string) bool {
+	_, _, err := parseDeezer(link)
+	return err == nil
+}
+
+func parseDeezer(link string) (kind, id string, err error) {
+	u, err := url.Parse(link)
+	if err != nil || (u.Host != "www.deezer.com" && u.Host != "deezer.com") {
+		return "", "", ErrUnsupportedDeezerLink
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) == 3 {
+		// Localized links start with the language.
+		parts = parts[1:]
+	}
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", ErrUnsupportedDeezerLink
+	}
+	switch parts[0] {
+	case "track", "album", "playlist":
+		return parts[0], parts[1], nil
+	}
+	return "", "", ErrUnsupportedDeezerLink
+}
+
+// Loads a track, album or playlist link through LavaSrc when the node has it and mirrors its tracks otherwise.
+func (d *Deezer) Resolve(ctx context.Context, node *lavago.Node, link string) ([]*lavago.Track, error) {
+	kind, id, err := parseDeezer(link)
+	if err != nil {
+		return nil, err
+	}
+	lavaSrc := d.hasDeezer(ctx, node)
+	if lavaSrc && kind != "track" {
+		sr, err := node.Search(lavago.Direct, link)
+		if err != nil {
+			return nil, err
+		}
+		return loaded(sr)
+	}
+	tracks, err := d.tracks(ctx, kind, id)
+	if err != nil {
+		return nil, err
+	}
+	if lavaSrc {
+		return d.loadTrack(node, tracks[0])
+	}
+	return d.mirror(ctx, node, tracks)
+}
+
+// Whether LavaSrc's deezer source is enabled on node.
+func (d *Deezer) hasDeezer(ctx context.Context, node *lavago.Node) bool {
+	if has, ok := d.sources.Load(node); ok {
+		return has.(bool)
+	}
+	info, err := node.Info(ctx)
+	if err != nil {
+		// Servers older than the info endpoint have no LavaSrc either, failed requests are retried.
+		return false
+	}
+	has := info.HasSource("deezer")
+	d.sources.Store(node, has)
+	return has
+}
+
+// Loads the track from Deezer through LavaSrc.
+func (d *Deezer) loadTrack(node *lavago.Node, t *DeezerTrack) ([]*lavago.Track, error) {
+	if t.ISRC != "" {
+		sr, err := node.Search(lavago.Direct, "dzisrc:"+t.ISRC)
+		if err != nil {
+			return nil, err
+		}
+		if tracks, err := loaded(sr); err == nil {
+			return tracks, nil
+		}
+	}
+	sr, err := node.Search(lavago.Direct, "dzsearch:"+t.Artist+" "+t.Title)
+	if err != nil {
+		return nil, err
+	}
+	return loaded(sr)
+}
+
+func (d *Deezer) mirror(ctx context.Context, node *lavago.Node, metas []*DeezerTrack) ([]*lavago.Track, error) {
+	concurrency := d.Concurrency
+	if concurrency < 1 {
+		concurrency = 4
+	}
+	wants := make([]*lavago.Track, len(metas))
+	for i, meta := range metas {
+		wants[i] = &lavago.Track{Info: meta.trackInfo(), UserData: meta}
+	}
+	tracks, err := node.MirrorMany(ctx, wants, concurrency)
+	if errors.Is(err, lavago.ErrNoMirror) {
+		return nil, ErrNoMatches
+	}
+	return tracks, err
+}
+
+func (t *DeezerTrack) trackInfo() lavago.TrackInfo {
+	return lavago.TrackInfo{
+		Identifier: fmt.Sprint(t.ID),
+		Title:      t.Title,
+		Author:     t.Artist,
+		Length:     t.Duration,
+		URL:        t.URL,
+		SourceName: "deezer",
+		Artwork:    t.Artwork,
+		ISRC:       t.ISRC,
+	}
+}
+
+type deezerTrack struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Duration int64  `json:"duration"`
+	ISRC     string `json:"isrc"`
+	Link     string `json:"link"`
+	Artist   struct {
+		Name string `json:"name"`
+	} `json:"artist"`
+	Album deezerAlbum `json:"album"`
+}
+
+type deezerAlbum struct {
+	Title   string `json:"title"`
+	CoverXL string `json:"cover_xl"`
+}
+
+type deezerPage struct {
+	Data []deezerTrack `json:"data"`
+	Next string        `json:"next"`
+}
+
+func (t *deezerTrack) metadata() *DeezerTrack {
+	return &DeezerTrack{
+		ID:       t.ID,
+		Title:    t.Title,
+		Artist:   t.Artist.Name,
+		Album:    t.Album.Title,
+		ISRC:     t.ISRC,
+		Duration: time.Duration(t.Duration) * time.Second,
+		URL:      t.Link,
+		Artwork:  t.Album.CoverXL,
+	}
+}
+
+// Fetches the metadata of every track behind a link, in order.
+func (d *Deezer) tracks(ctx context.Context, kind, id string) ([]*DeezerTrack, error) {
+	if kind == "track" {
+		t := deezerTrack{}
+		err := d.get(ctx, deezerAPIURL+"/track/"+url.PathEscape(id), &t)
+		if err != nil {
+			return nil, err
+		}
+		return []*DeezerTrack{t.metadata()}, nil
+	}
+	album := deezerAlbum{}
+	if kind == "album" {
+		// Album track lists leave out the album itself.
+		err := d.get(ctx, deezerAPIURL+"/album/"+url.PathEscape(id), &album)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var metas []*DeezerTrack
+	next := fmt.Sprintf("%s/%s/%s/tracks?limit=100", deezerAPIURL, kind, url.PathEscape(id))
+	for next != "" {
+		page := deezerPage{}
+		if err := d.get(ctx, next, &page); err != nil {
+			return nil, err
+		}
+		for i := range page.Data {
+			t := &page.Data[i]
+			if kind == "album" {
+				t.Album = album
+			}
+			metas = append(metas, t.metadata())
+		}
+		next = page.Next
+	}
+	if len(metas) == 0 {
+		return nil, ErrNoMatches
+	}
+	return metas, nil
+}
+
+func (d *Deezer) get(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+	hc := d.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("deezer responded with %v", res.Status)
+	}
+	// Deezer reports errors with a 200 status.
+	body := struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}{}
+	raw := json.RawMessage{}
+	if err := json.NewDecoder(res.Body).Decode(&raw); err != nil {
+		return err
+	}
+	if json.Unmarshal(raw, &body) == nil && body.Error != nil {
+		return fmt.Errorf("deezer: %v", body.Error.Message)
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,65 @@
+// Package resolver routes links to the resolver for their service and loads everything else
+// through Lavalink.
+//
+//	router := resolver.New(spotify.New(id, secret), applemusic.New(""), resolver.NewDeezer())
+//	tracks, err := router.Resolve(ctx, node, query)
+package resolver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nemphi/lavago"
+)
+
+// Returned when nothing was found for a query.
+var ErrNoMatches = errors.New("no matches found")
+
+// Resolves the links of one service.
+type LinkResolver interface {
+	// Whether the resolver handles link.
+	Match(link string) bool
+	Resolve(ctx context.Context, node *lavago.Node, link string) ([]*lavago.Track, error)
+}
+
+// Picks the first resolver matching a query.
+type Router struct {
+	Resolvers []LinkResolver
+}
+
+// Creates a router trying the resolvers in order.
+func New(resolvers ...LinkResolver) *Router {
+	return &Router{Resolvers: resolvers}
+}
+
+// Resolves query with the first matching resolver. Other queries are passed to `lavago.Node.Resolve`,
+// which loads links directly and searches anything else, returning only the best search result.
+func (r *Router) Resolve(ctx context.Context, node *lavago.Node, query string) ([]*lavago.Track, error) {
+	for _, lr := range r.Resolvers {
+		if lr.Match(query) {
+			return lr.Resolve(ctx, node, query)
+		}
+	}
+	sr, err := node.Resolve(query)
+	if err != nil {
+		return nil, err
+	}
+	return loaded(sr)
+}
+
+// Tracks of a load result, only the first one for searches.
+func loaded(sr *lavago.SearchResult) ([]*lavago.Track, error) {
+	switch sr.Status {
+	case lavago.LoadFailedSearchStatus:
+		return nil, errors.New(sr.Exception.Message)
+	case lavago.SearchResultSearchStatus:
+		if len(sr.Tracks) > 0 {
+			return sr.Tracks[:1], nil
+		}
+	default:
+		if len(sr.Tracks) > 0 {
+			return sr.Tracks, nil
+		}
+	}
+	return nil, ErrNoMatches
+}
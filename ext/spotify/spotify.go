@@ -0,0 +1,346 @@
+// Package spotify plays Spotify links on servers without a Spotify plugin like LavaSrc, by reading
+// their metadata from Spotify's Web API and mirroring every track to YouTube Music.
+//
+//	client := spotify.New(clientID, clientSecret)
+//	tracks, err := client.Resolve(ctx, node, "https://open.spotify.com/playlist/37i9dQZF1DXcBWIGoYBM5M")
+//	meta := tracks[0].UserData.(*spotify.Metadata)
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nemphi/lavago"
+)
+
+const (
+	tokenURL = "https://accounts.spotify.com/api/token"
+	apiURL   = "https://api.spotify.com/v1"
+)
+
+// Mirrors resolved at once when Client.Concurrency isn't set.
+const DefaultConcurrency = 4
+
+// Returned when a link isn't a Spotify track, album or playlist.
+var ErrUnsupportedLink = errors.New("not a spotify track, album or playlist link")
+
+// Returned by Resolve when none of the link's tracks could be mirrored.
+var ErrNoTracks = errors.New("no spotify track could be mirrored")
+
+// Kind of Spotify link.
+type Kind string
+
+const (
+	KindTrack    Kind = "track"
+	KindAlbum    Kind = "album"
+	KindPlaylist Kind = "playlist"
+)
+
+// Spotify's metadata of a track, attached as UserData to the mirrored `lavago.Track`.
+type Metadata struct {
+	ID       string
+	Title    string
+	Artists  []string
+	Album    string
+	ISRC     string
+	Duration time.Duration
+	// Link to the track on Spotify.
+	URL string
+	// Url of the album cover, empty if unknown.
+	Artwork string
+}
+
+// Spotify Web API client authenticating with the client credentials flow.
+type Client struct {
+	ClientID     string
+	ClientSecret string
+	// Used for Spotify's API. Defaults to `http.DefaultClient`.
+	HTTPClient *http.Client
+	// How many tracks are mirrored at once. Defaults to `DefaultConcurrency`.
+	Concurrency int
+
+	token   string
+	expires time.Time
+	mu      sync.Mutex
+}
+
+// Creates a client for the application with the given credentials.
+func New(clientID, clientSecret string) *Client {
+	return &Client{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Concurrency:  DefaultConcurrency,
+	}
+}
+
+// Whether link is a Spotify link Resolve handles.
+func IsLink(link string) bool {
+	_, _, err := Parse(link)
+	return err == nil
+}
+
+// Same as IsLink, so the client can be used as a resolver.LinkResolver.
+func (c *Client) Match(link string) bool {
+	return IsLink(link)
+}
+
+// Returns the kind and ID of a link like "https://open.spotify.com/intl-de/track/{id}?si=..."
+// or "spotify:track:{id}".
+func Parse(link string) (Kind, string, error) {
+	var parts []string
+	if strings.HasPrefix(link, "spotify:") {
+		parts = strings.Split(strings.TrimPrefix(link, "spotify:"), ":")
+	} else {
+		u, err := url.Parse(link)
+		if err != nil || u.Host != "open.spotify.com" {
+			return "", "", ErrUnsupportedLink
+		}
+		parts = strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) > 0 && strings.HasPrefix(parts[0], "intl-") {
+			parts = parts[1:]
+		}
+	}
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", ErrUnsupportedLink
+	}
+	switch kind := Kind(parts[0]); kind {
+	case KindTrack, KindAlbum, KindPlaylist:
+		return kind, parts[1], nil
+	}
+	return "", "", ErrUnsupportedLink
+}
+
+// Fetches the metadata of every track behind the link, in order.
+func (c *Client) Metadata(ctx context.Context, link string) ([]*Metadata, error) {
+	kind, id, err := Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case KindTrack:
+		t := apiTrack{}
+		err = c.get(ctx, apiURL+"/tracks/"+id, &t)
+		if err != nil {
+			return nil, err
+		}
+		return []*Metadata{t.metadata(nil)}, nil
+	case KindAlbum:
+		return c.album(ctx, id)
+	default:
+		return c.playlist(ctx, id)
+	}
+}
+
+// Fetches the link's metadata and mirrors each track through `lavago.Node.Mirror`, setting
+// the Metadata as the track's UserData. Tracks without a match are left out, it only fails
+// if none matched, with the node's error if it couldn't be reached.
+func (c *Client) Resolve(ctx context.Context, node *lavago.Node, link string) ([]*lavago.Track, error) {
+	metas, err := c.Metadata(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	concurrency := c.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+	wants := make([]*lavago.Track, len(metas))
+	for i, meta := range metas {
+		wants[i] = &lavago.Track{Info: meta.trackInfo(), UserData: meta}
+	}
+	tracks, err := node.MirrorMany(ctx, wants, concurrency)
+	if errors.Is(err, lavago.ErrNoMirror) {
+		return nil, ErrNoTracks
+	}
+	return tracks, err
+}
+
+func (m *Metadata) trackInfo() lavago.TrackInfo {
+	return lavago.TrackInfo{
+		Identifier: m.ID,
+		Title:      m.Title,
+		Author:     strings.Join(m.Artists, ", "),
+		Length:     m.Duration,
+		URL:        m.URL,
+		SourceName: "spotify",
+		Artwork:    m.Artwork,
+		ISRC:       m.ISRC,
+	}
+}
+
+type apiTrack struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	DurationMs int64  `json:"duration_ms"`
+	Artists    []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	Album       *apiAlbum `json:"album"`
+	ExternalIDs struct {
+		ISRC string `json:"isrc"`
+	} `json:"external_ids"`
+	ExternalURLs struct {
+		Spotify string `json:"spotify"`
+	} `json:"external_urls"`
+}
+
+type apiAlbum struct {
+	Name   string `json:"name"`
+	Images []struct {
+		URL string `json:"url"`
+	} `json:"images"`
+	Tracks apiPage `json:"tracks"`
+}
+
+type apiPage struct {
+	Items []json.RawMessage `json:"items"`
+	Next  string            `json:"next"`
+}
+
+// album is used for tracks listed by an album, which don't carry it themselves.
+func (t *apiTrack) metadata(album *apiAlbum) *Metadata {
+	if t.Album != nil {
+		album = t.Album
+	}
+	m := &Metadata{
+		ID:       t.ID,
+		Title:    t.Name,
+		ISRC:     t.ExternalIDs.ISRC,
+		Duration: time.Duration(t.DurationMs) * time.Millisecond,
+		URL:      t.ExternalURLs.Spotify,
+	}
+	for _, a := range t.Artists {
+		m.Artists = append(m.Artists, a.Name)
+	}
+	if album != nil {
+		m.Album = album.Name
+		if len(album.Images) > 0 {
+			m.Artwork = album.Images[0].URL
+		}
+	}
+	return m
+}
+
+func (c *Client) album(ctx context.Context, id string) ([]*Metadata, error) {
+	album := apiAlbum{}
+	err := c.get(ctx, apiURL+"/albums/"+id, &album)
+	if err != nil {
+		return nil, err
+	}
+	var metas []*Metadata
+	err = c.pages(ctx, album.Tracks, func(item json.RawMessage) error {
+		t := apiTrack{}
+		if err := json.Unmarshal(item, &t); err != nil {
+			return err
+		}
+		metas = append(metas, t.metadata(&album))
+		return nil
+	})
+	return metas, err
+}
+
+func (c *Client) playlist(ctx context.Context, id string) ([]*Metadata, error) {
+	first := apiPage{}
+	err := c.get(ctx, apiURL+"/playlists/"+id+"/tracks?limit=100", &first)
+	if err != nil {
+		return nil, err
+	}
+	var metas []*Metadata
+	err = c.pages(ctx, first, func(item json.RawMessage) error {
+		entry := struct {
+			Track *apiTrack `json:"track"`
+		}{}
+		if err := json.Unmarshal(item, &entry); err != nil {
+			return err
+		}
+		// Removed tracks and podcast episodes can't be mirrored.
+		if entry.Track == nil || entry.Track.ID == "" {
+			return nil
+		}
+		metas = append(metas, entry.Track.metadata(nil))
+		return nil
+	})
+	return metas, err
+}
+
+// Calls fn with every item of page and the pages following it.
+func (c *Client) pages(ctx context.Context, page apiPage, fn func(json.RawMessage) error) error {
+	for {
+		for _, item := range page.Items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if page.Next == "" {
+			return nil
+		}
+		next := page.Next
+		page = apiPage{}
+		if err := c.get(ctx, next, &page); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context, u string, v interface{}) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return c.do(req, v)
+}
+
+// Returns the cached access token, requesting a new one when it expired.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.expires) {
+		return c.token, nil
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(c.ClientID, c.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}{}
+	err = c.do(req, &res)
+	if err != nil {
+		return "", err
+	}
+	c.token = res.AccessToken
+	// Renew a minute early so requests never race the expiry.
+	c.expires = time.Now().Add(time.Duration(res.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+func (c *Client) do(req *http.Request, v interface{}) error {
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("spotify responded with %v", res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
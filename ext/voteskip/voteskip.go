@@ -0,0 +1,117 @@
+// Package voteskip lets listeners vote to skip the current track.
+//
+//	votes := voteskip.New(func(guildID string) int {
+//		return listenersInVoice(guildID)
+//	})
+//	skipped, count, needed, err := votes.VoteSkip(player, userID)
+package voteskip
+
+import (
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/nemphi/lavago"
+)
+
+// Share of listeners that must vote when Manager.Ratio isn't set.
+const DefaultRatio = 0.5
+
+// Tracks skip votes per guild. Votes reset whenever the guild's player moves on to another track.
+// The zero value is ready to use.
+type Manager struct {
+	// Share of listeners that must vote to skip, from 0 to 1. Defaults to `DefaultRatio`.
+	Ratio float64
+	// Returns how many users, not counting the bot, are in the guild's voice channel.
+	Listeners func(guildID string) int
+
+	guilds map[string]*ballot
+	mu     sync.Mutex
+}
+
+// Votes for one track.
+type ballot struct {
+	track  *lavago.Track
+	voters map[string]bool
+}
+
+// Creates a manager counting listeners with the given func.
+func New(listeners func(guildID string) int) *Manager {
+	return &Manager{
+		Ratio:     DefaultRatio,
+		Listeners: listeners,
+	}
+}
+
+// Records userID's vote to skip the player's current track and skips it once enough
+// listeners voted. Voting twice for the same track counts once. Returns how many votes the
+// track has and how many it needs. The votes are kept if skipping fails.
+func (m *Manager) VoteSkip(p *lavago.Player, userID string) (skipped bool, votes, needed int, err error) {
+	p.RLock()
+	track := p.Track
+	p.RUnlock()
+	if track == nil {
+		return false, 0, 0, errors.New("nothing to skip, no track is playing")
+	}
+	needed = m.needed(p.GuildID)
+
+	m.mu.Lock()
+	b := m.guilds[p.GuildID]
+	if b == nil || b.track != track {
+		b = &ballot{track: track, voters: map[string]bool{}}
+		if m.guilds == nil {
+			m.guilds = map[string]*ballot{}
+		}
+		m.guilds[p.GuildID] = b
+	}
+	b.voters[userID] = true
+	votes = len(b.voters)
+	m.mu.Unlock()
+
+	if votes < needed {
+		return false, votes, needed, nil
+	}
+	if _, _, err := p.SkipNow(); err != nil {
+		return false, votes, needed, err
+	}
+	m.Reset(p.GuildID)
+	return true, votes, needed, nil
+}
+
+// Votes the current track of the guild has, zero if the player moved on since.
+func (m *Manager) Votes(p *lavago.Player) int {
+	p.RLock()
+	track := p.Track
+	p.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b := m.guilds[p.GuildID]
+	if b == nil || b.track != track {
+		return 0
+	}
+	return len(b.voters)
+}
+
+// Drops the guild's votes, i.e. once its player is destroyed.
+func (m *Manager) Reset(guildID string) {
+	m.mu.Lock()
+	delete(m.guilds, guildID)
+	m.mu.Unlock()
+}
+
+// Votes needed to skip in the guild, at least one.
+func (m *Manager) needed(guildID string) int {
+	ratio := m.Ratio
+	if ratio <= 0 || ratio > 1 {
+		ratio = DefaultRatio
+	}
+	listeners := 0
+	if m.Listeners != nil {
+		listeners = m.Listeners(guildID)
+	}
+	needed := int(math.Ceil(float64(listeners) * ratio))
+	if needed < 1 {
+		needed = 1
+	}
+	return needed
+}
@@ -0,0 +1,110 @@
+package lavago
+
+import "time"
+
+// Information about a guild moved to another node after its node gave up reconnecting.
+type FailoverEvent struct {
+	GuildID string
+	// Node that gave up.
+	From *Node
+	// Node now holding the guild's player, nil if the move failed.
+	To *Node
+	// The guild's new player, nil if the move failed.
+	Player *Player
+	// Why the guild couldn't be moved, i.e. a *PinnedNodeUnavailableError or ErrNoNodeAvailable.
+	Err error
+}
+
+// What a dropped player was doing, carried over to its replacement on another node.
+type lostPlayer struct {
+	guildID   string
+	channelID string
+	track     *Track
+	position  time.Duration
+	paused    bool
+	volume    int
+	loop      LoopMode
+	queue     []*Track
+}
+
+// Records every player's playback before the node drops them. Must be called before
+// endLostTracks, which stops the players.
+func (n *Node) snapshotPlayers() []lostPlayer {
+	var lost []lostPlayer
+	n.players.Range(func(_, v interface{}) bool {
+		p := v.(*Player)
+		p.RLock()
+		lp := lostPlayer{
+			guildID:   p.GuildID,
+			channelID: p.ChannelID,
+			volume:    p.Volume,
+			loop:      p.loop,
+		}
+		state := p.State
+		if state == PlayerStateBuffering {
+			state = p.buffered
+		}
+		if state == PlayerStatePlaying || state == PlayerStatePaused {
+			lp.track, lp.position = p.Track, p.position()
+			lp.paused = state == PlayerStatePaused
+		}
+		p.RUnlock()
+		lp.queue = p.Queue.Values()
+		lost = append(lost, lp)
+		return true
+	})
+	return lost
+}
+
+// Hands the dropped players to the node's pool, if any.
+func (n *Node) playersDropped(lost []lostPlayer, failover bool) {
+	n.mu.RLock()
+	dropped := n.dropped
+	n.mu.RUnlock()
+	if dropped != nil {
+		dropped(lost, failover)
+	}
+}
+
+// Continues where the lost player stopped.
+func (lp lostPlayer) restore(p *Player) error {
+	p.Queue.Add(lp.queue...)
+	p.SetLoop(lp.loop)
+	if lp.track == nil {
+		return nil
+	}
+	return p.Play(PlayArgs{Track: lp.track, StartTime: lp.position, Volume: lp.volume, ShouldPause: lp.paused})
+}
+
+// Forgets the guilds of players the node dropped and, with failover, joins them on another
+// node. Their assigned node is preferred if it's available, pinned guilds only move back to
+// their pinned node.
+func (pl *Pool) playersDropped(from *Node, lost []lostPlayer, failover bool) {
+	for _, lp := range lost {
+		if pl.nodeOf(lp.guildID) != from {
+			continue
+		}
+		pl.players.Delete(lp.guildID)
+		if !failover || lp.channelID == "" {
+			continue
+		}
+		e := FailoverEvent{GuildID: lp.guildID, From: from}
+		e.Player, e.Err = pl.join(lp.guildID, lp.channelID, map[*Node]bool{from: true})
+		if e.Err == nil {
+			e.To = e.Player.node
+			e.Err = lp.restore(e.Player)
+		}
+		if pl.PlayerFailedOver != nil {
+			pl.PlayerFailedOver(e)
+		}
+	}
+}
+
+// Lets the pool fail over the node's players.
+func (pl *Pool) watch(n *Node) {
+	n.mu.Lock()
+	n.dropped = func(lost []lostPlayer, failover bool) {
+		pl.playersDropped(n, lost, failover)
+	}
+	n.mu.Unlock()
+}
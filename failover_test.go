@@ -0,0 +1,101 @@
+package lavago
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Pool of two nodes on fake servers, the first one giving up after a single failed reconnect.
+func failoverPool(t *testing.T) (pl *Pool, a, b *Node, fa, fb *fakeLavalink) {
+	fa, fb = newFakeLavalink(t), newFakeLavalink(t)
+	cfg := fa.config(t)
+	cfg.ReconnectAttempts = 1
+	cfg.Backoff = ConstantBackoff{Interval: time.Millisecond}
+	a, b = fa.connect(t, cfg), fb.node(t)
+	pl = NewPool(a, b)
+	return pl, a, b, fa, fb
+}
+
+func TestPoolFailover(t *testing.T) {
+	pl, a, b, fa, fb := failoverPool(t)
+	events := make(chan FailoverEvent, 1)
+	pl.PlayerFailedOver = func(e FailoverEvent) { events <- e }
+	pl.Affinity.Store("1", Affinity{NodeID: a.ID()})
+	p, err := pl.Join("1", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Queue.Add(queued("next")...)
+	if err := p.Play(PlayArgs{Track: &Track{Track: "lost", Info: TrackInfo{Length: time.Hour}}, Volume: 50}); err != nil {
+		t.Fatal(err)
+	}
+	// As reported by a playerUpdate.
+	p.Lock()
+	p.Track.Info.Position, p.positionAt = time.Minute, time.Now()
+	p.Unlock()
+	fa.drop(websocket.CloseServiceRestart, true)
+	var e FailoverEvent
+	select {
+	case e = <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("guild wasn't failed over")
+	}
+	if e.Err != nil || e.From != a || e.To != b {
+		t.Fatalf("failed over from %v to %v: %v", e.From.ID(), e.To, e.Err)
+	}
+	if pl.GetPlayer("1") != e.Player || b.GetPlayer("1") != e.Player {
+		t.Error("pool doesn't route the guild to its new player")
+	}
+	if got := queueNames(e.Player.Queue); !equalNames(got, []string{"next"}) {
+		t.Errorf("queue = %v, want [next]", got)
+	}
+	op := fb.next(t, func(op map[string]interface{}) bool { return op["op"] == "play" })
+	if op["track"] != "lost" || op["startTime"].(float64) < float64(time.Minute/time.Millisecond) {
+		t.Errorf("resumed with %v", op)
+	}
+	if aff, _, _ := pl.Affinity.Load("1"); aff.NodeID != b.ID() {
+		t.Errorf("guild assigned to %v, want %v", aff.NodeID, b.ID())
+	}
+}
+
+func TestPoolFailoverKeepsPins(t *testing.T) {
+	pl, a, _, fa, _ := failoverPool(t)
+	events := make(chan FailoverEvent, 1)
+	pl.PlayerFailedOver = func(e FailoverEvent) { events <- e }
+	if err := pl.PinGuild("1", a.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pl.Join("1", "2"); err != nil {
+		t.Fatal(err)
+	}
+	fa.drop(websocket.CloseServiceRestart, true)
+	select {
+	case e := <-events:
+		var pe *PinnedNodeUnavailableError
+		if !errors.As(e.Err, &pe) || e.Player != nil {
+			t.Errorf("pinned guild failed over to %v: %v", e.To, e.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no failover event")
+	}
+	if pl.GetPlayer("1") != nil || pl.nodeOf("1") != nil {
+		t.Error("pool kept the dropped player")
+	}
+}
+
+func TestPoolForgetsPlayersOfClosedNodes(t *testing.T) {
+	pl, _, _, _, _ := failoverPool(t)
+	if _, err := pl.Join("1", "2"); err != nil {
+		t.Fatal(err)
+	}
+	n := pl.nodeOf("1")
+	if err := n.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if pl.nodeOf("1") != nil {
+		t.Error("pool kept the closed node's player")
+	}
+}
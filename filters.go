@@ -0,0 +1,450 @@
+package lavago
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"time"
+)
+
+// Gain applied to one of the equalizer's 15 bands, from 0 (25 Hz) to 14 (16 kHz).
+type EqualizerBand struct {
+	Band int `json:"band"`
+	// Multiplier from -0.25 (muted) to 1.0 (doubled), 0 leaves the band unchanged.
+	Gain float64 `json:"gain"`
+}
+
+// Changes the speed, pitch and playback rate. 1.0 leaves each unchanged.
+type Timescale struct {
+	Speed float64 `json:"speed,omitempty"`
+	Pitch float64 `json:"pitch,omitempty"`
+	Rate  float64 `json:"rate,omitempty"`
+}
+
+// Pans the audio around the listener, the "8D audio" effect.
+type Rotation struct {
+	// Rotations per second.
+	RotationHz float64 `json:"rotationHz"`
+}
+
+// Cancels sound around a frequency band, meant to remove vocals.
+type Karaoke struct {
+	// Effect strength from 0 to 1.
+	Level float64 `json:"level"`
+	// Strength applied to the mono (center) signal from 0 to 1.
+	MonoLevel float64 `json:"monoLevel"`
+	// Center of the cancelled band in Hz.
+	FilterBand float64 `json:"filterBand"`
+	// Width of the cancelled band in Hz.
+	FilterWidth float64 `json:"filterWidth"`
+}
+
+// Mixes the left and right channels. Each factor ranges from 0 to 1, where
+// LeftToLeft and RightToRight of 1 with the others 0 leaves the audio unchanged.
+type ChannelMix struct {
+	LeftToLeft   float64 `json:"leftToLeft"`
+	LeftToRight  float64 `json:"leftToRight"`
+	RightToLeft  float64 `json:"rightToLeft"`
+	RightToRight float64 `json:"rightToRight"`
+}
+
+// Channel mixes applied by Player.SetMono and Player.SwapChannels.
+var (
+	MonoChannelMix    = ChannelMix{LeftToLeft: 0.5, LeftToRight: 0.5, RightToLeft: 0.5, RightToRight: 0.5}
+	SwappedChannelMix = ChannelMix{LeftToRight: 1, RightToLeft: 1}
+)
+
+// Suppresses higher frequencies, smoothing the audio.
+type LowPass struct {
+	// Values above 1 enable the filter, higher values cut more treble.
+	Smoothing float64 `json:"smoothing"`
+}
+
+// Audio filters applied to a player's output. Nil or empty filters are disabled.
+type Filters struct {
+	// Volume multiplier from 0 to 5, 1 leaves the volume unchanged. Unlike the volume op
+	// it allows fractional values and is applied along with the other filters.
+	Volume     *float64        `json:"volume,omitempty"`
+	Equalizer  []EqualizerBand `json:"equalizer,omitempty"`
+	Timescale  *Timescale      `json:"timescale,omitempty"`
+	Rotation   *Rotation       `json:"rotation,omitempty"`
+	Karaoke    *Karaoke        `json:"karaoke,omitempty"`
+	ChannelMix *ChannelMix     `json:"channelMix,omitempty"`
+	LowPass    *LowPass        `json:"lowPass,omitempty"`
+	// Filters provided by server plugins keyed by name, i.e. "echo" or "reverb" from
+	// lavalink-filter-plugins. Values are sent as JSON. Only supported by Lavalink v4.
+	Plugin map[string]interface{} `json:"pluginFilters,omitempty"`
+}
+
+// Deep copy of f.
+func (f Filters) clone() Filters {
+	c := f
+	if f.Volume != nil {
+		v := *f.Volume
+		c.Volume = &v
+	}
+	if f.Equalizer != nil {
+		c.Equalizer = append([]EqualizerBand(nil), f.Equalizer...)
+	}
+	if f.Timescale != nil {
+		ts := *f.Timescale
+		c.Timescale = &ts
+	}
+	if f.Rotation != nil {
+		r := *f.Rotation
+		c.Rotation = &r
+	}
+	if f.Karaoke != nil {
+		k := *f.Karaoke
+		c.Karaoke = &k
+	}
+	if f.ChannelMix != nil {
+		cm := *f.ChannelMix
+		c.ChannelMix = &cm
+	}
+	if f.LowPass != nil {
+		lp := *f.LowPass
+		c.LowPass = &lp
+	}
+	if f.Plugin != nil {
+		c.Plugin = make(map[string]interface{}, len(f.Plugin))
+		for k, v := range f.Plugin {
+			c.Plugin[k] = v
+		}
+	}
+	return c
+}
+
+// Returned when changing filters other than the equalizer on a server without the filters op.
+var ErrFiltersUnsupported = errors.New("lavalink server doesn't support filters")
+
+// Presets applied by Player.Nightcore and Player.Vaporwave.
+var (
+	NightcoreTimescale = Timescale{Speed: 1.2, Pitch: 1.2, Rate: 1}
+	VaporwaveTimescale = Timescale{Speed: 0.85, Pitch: 0.8, Rate: 1}
+)
+
+// Karaoke settings used by Player.RemoveVocals, cancelling the typical vocal range.
+var DefaultKaraoke = Karaoke{Level: 1, MonoLevel: 1, FilterBand: 220, FilterWidth: 100}
+
+func validateBands(bands []EqualizerBand) error {
+	for _, b := range bands {
+		if b.Band < 0 || b.Band > 14 {
+			return errors.New("equalizer band must be between 0 and 14")
+		}
+		if b.Gain < -0.25 || b.Gain > 1 {
+			return errors.New("equalizer gain must be between -0.25 and 1.0")
+		}
+	}
+	return nil
+}
+
+// Sets the equalizer's bands through the filters op when the server supports it,
+// falling back to the legacy equalizer op for Lavalink versions before 3.4.
+func (p *Player) SetBands(bands ...EqualizerBand) error {
+	ok, err := p.supportsFilters()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return p.SetBandsV3(bands...)
+	}
+	if err := validateBands(bands); err != nil {
+		return err
+	}
+	return p.UpdateFilters(func(f *Filters) {
+		f.Equalizer = append([]EqualizerBand(nil), bands...)
+	})
+}
+
+// Sets the equalizer's bands with the legacy equalizer op, for Lavalink versions before 3.4.
+// Newer servers still accept it but it resets any other filter. Lavalink v4 has no such op,
+// other filters stay in place there.
+func (p *Player) SetBandsV3(bands ...EqualizerBand) error {
+	if err := validateBands(bands); err != nil {
+		return err
+	}
+	p.filtersMu.Lock()
+	defer p.filtersMu.Unlock()
+	p.RLock()
+	current := p.filters.clone()
+	p.RUnlock()
+	msg, err := p.codec().EncodeEqualizer(p.GuildID, bands, current)
+	if err != nil {
+		return err
+	}
+	err = p.send(msg)
+	if err != nil {
+		return err
+	}
+	f := Filters{}
+	if p.node != nil && p.node.APIVersion() >= 4 {
+		f = current
+	}
+	f.Equalizer = append([]EqualizerBand(nil), bands...)
+	p.Lock()
+	p.filters = f
+	p.Unlock()
+	return nil
+}
+
+// Speeds up and pitches up playback, or turns the timescale filter off.
+// Replaces Vaporwave and other timescale changes, leaving other filters in place.
+func (p *Player) Nightcore(on bool) error {
+	return p.setTimescale(on, NightcoreTimescale)
+}
+
+// Slows down and pitches down playback, or turns the timescale filter off.
+// Replaces Nightcore and other timescale changes, leaving other filters in place.
+func (p *Player) Vaporwave(on bool) error {
+	return p.setTimescale(on, VaporwaveTimescale)
+}
+
+func (p *Player) setTimescale(on bool, ts Timescale) error {
+	return p.UpdateFilters(func(f *Filters) {
+		f.Timescale = nil
+		if on {
+			f.Timescale = &ts
+		}
+	})
+}
+
+// Rotates the audio around the listener at speedHz rotations per second, 0.2 being a
+// common choice for "8D audio". Zero turns the rotation off, other filters stay in place.
+func (p *Player) Rotate8D(speedHz float64) error {
+	if speedHz < 0 {
+		return errors.New("rotation speed can't be negative")
+	}
+	return p.UpdateFilters(func(f *Filters) {
+		f.Rotation = nil
+		if speedHz > 0 {
+			f.Rotation = &Rotation{RotationHz: speedHz}
+		}
+	})
+}
+
+// Copy of the filters last applied to the player.
+func (p *Player) Filters() Filters {
+	p.RLock()
+	defer p.RUnlock()
+	return p.filters.clone()
+}
+
+// Sets every filter at once.
+func (p *Player) SetFilters(f Filters) error {
+	return p.UpdateFilters(func(current *Filters) {
+		*current = f.clone()
+	})
+}
+
+// Turns off every filter.
+func (p *Player) ClearFilters() error {
+	return p.SetFilters(Filters{})
+}
+
+// Cancels sound around band Hz, width Hz wide. level and monoLevel range from 0 to 1.
+// Other filters stay in place.
+func (p *Player) SetKaraoke(level, monoLevel, band, width float64) error {
+	if level < 0 || level > 1 || monoLevel < 0 || monoLevel > 1 {
+		return errors.New("karaoke levels must be between 0 and 1")
+	}
+	if band <= 0 || width <= 0 {
+		return errors.New("karaoke band and width must be positive")
+	}
+	return p.UpdateFilters(func(f *Filters) {
+		f.Karaoke = &Karaoke{Level: level, MonoLevel: monoLevel, FilterBand: band, FilterWidth: width}
+	})
+}
+
+// Turns on the karaoke filter with `DefaultKaraoke`.
+func (p *Player) RemoveVocals() error {
+	k := DefaultKaraoke
+	return p.SetKaraoke(k.Level, k.MonoLevel, k.FilterBand, k.FilterWidth)
+}
+
+// Turns off the karaoke filter, other filters stay in place.
+func (p *Player) DisableKaraoke() error {
+	return p.UpdateFilters(func(f *Filters) {
+		f.Karaoke = nil
+	})
+}
+
+// Mixes both channels into each ear, useful for listeners with hearing in one ear only
+// or for one-sided source material.
+func (p *Player) SetMono() error {
+	return p.SetChannelMix(MonoChannelMix)
+}
+
+// Plays the left channel on the right and the right channel on the left.
+func (p *Player) SwapChannels() error {
+	return p.SetChannelMix(SwappedChannelMix)
+}
+
+// Sets the channel mix matrix, other filters stay in place.
+func (p *Player) SetChannelMix(mix ChannelMix) error {
+	for _, v := range []float64{mix.LeftToLeft, mix.LeftToRight, mix.RightToLeft, mix.RightToRight} {
+		if v < 0 || v > 1 {
+			return errors.New("channel mix factors must be between 0 and 1")
+		}
+	}
+	return p.UpdateFilters(func(f *Filters) {
+		f.ChannelMix = &mix
+	})
+}
+
+// Turns off the channel mix filter, other filters stay in place.
+func (p *Player) ResetChannelMix() error {
+	return p.UpdateFilters(func(f *Filters) {
+		f.ChannelMix = nil
+	})
+}
+
+// Sets the low pass smoothing at once, values of 1 or less turn the filter off.
+// Other filters stay in place.
+func (p *Player) SetLowPass(smoothing float64) error {
+	return p.UpdateFilters(func(f *Filters) {
+		f.LowPass = nil
+		if smoothing > 1 {
+			f.LowPass = &LowPass{Smoothing: smoothing}
+		}
+	})
+}
+
+// Interval between the updates sent by Player.RampLowPass.
+const lowPassRampStep = 250 * time.Millisecond
+
+// Gradually moves the low pass smoothing from its current value to smoothing over the given
+// duration, avoiding an abrupt change. Values of 1 or less turn the filter off once reached.
+// Blocks until the ramp is done or ctx is cancelled, leaving the last sent value in place.
+func (p *Player) RampLowPass(ctx context.Context, smoothing float64, over time.Duration) error {
+	from := 1.0
+	if lp := p.Filters().LowPass; lp != nil {
+		from = lp.Smoothing
+	}
+	to := math.Max(smoothing, 1)
+	steps := int(over / lowPassRampStep)
+	ticker := time.NewTicker(lowPassRampStep)
+	defer ticker.Stop()
+	for i := 1; i < steps; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		err := p.SetLowPass(from + (to-from)*float64(i)/float64(steps))
+		if err != nil {
+			return err
+		}
+	}
+	if steps > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return p.SetLowPass(to)
+}
+
+// Sets a fractional volume multiplier from 0 to 5, 1 being the original volume. Uses the
+// volume filter when the server supports filters and the volume op otherwise, rounding
+// gain to whole percents.
+func (p *Player) SetGain(gain float64) error {
+	if gain < 0 || gain > 5 {
+		return errors.New("gain must be between 0 and 5")
+	}
+	ok, err := p.supportsFilters()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return p.UpdateVolume(int(math.Round(gain * 100)))
+	}
+	return p.UpdateFilters(func(f *Filters) {
+		f.Volume = nil
+		if gain != 1 {
+			f.Volume = &gain
+		}
+	})
+}
+
+// Sets a filter provided by a server plugin, other filters stay in place.
+func (p *Player) SetPluginFilter(name string, value interface{}) error {
+	return p.UpdateFilters(func(f *Filters) {
+		if f.Plugin == nil {
+			f.Plugin = map[string]interface{}{}
+		}
+		f.Plugin[name] = value
+	})
+}
+
+// Turns off a filter provided by a server plugin, other filters stay in place.
+func (p *Player) RemovePluginFilter(name string) error {
+	return p.UpdateFilters(func(f *Filters) {
+		delete(f.Plugin, name)
+		if len(f.Plugin) == 0 {
+			f.Plugin = nil
+		}
+	})
+}
+
+// Whether the player's node accepts the filters op. Only asks the server when the check made
+// on connecting failed.
+func (p *Player) supportsFilters() (bool, error) {
+	if p.node == nil {
+		return false, nil
+	}
+	return p.node.SupportsFilters(context.Background())
+}
+
+// Applies fn to a copy of the player's current filters and sends the result, keeping it as
+// the current filters once Lavalink accepted it. Lets a single filter be changed while
+// keeping the others:
+//
+//	p.UpdateFilters(func(f *Filters) {
+//		f.Rotation = &Rotation{RotationHz: 0.2}
+//	})
+func (p *Player) UpdateFilters(fn func(*Filters)) error {
+	ok, err := p.supportsFilters()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrFiltersUnsupported
+	}
+	p.filtersMu.Lock()
+	defer p.filtersMu.Unlock()
+	p.RLock()
+	f := p.filters.clone()
+	p.RUnlock()
+	fn(&f)
+	msg, err := p.codec().EncodeFilters(p.GuildID, f)
+	if err != nil {
+		return err
+	}
+	err = p.send(msg)
+	if err != nil {
+		return err
+	}
+	p.Lock()
+	p.filters = f
+	p.Unlock()
+	return nil
+}
+
+// Clears the filters after the track was replaced if `Config.ResetFiltersOnTrackChange` is set.
+func (p *Player) trackReplaced() {
+	if !p.resetFilters {
+		return
+	}
+	p.RLock()
+	active := !reflect.DeepEqual(p.filters, Filters{})
+	p.RUnlock()
+	if !active {
+		return
+	}
+	if err := p.ClearFilters(); err != nil && p.node != nil {
+		p.node.socketOnError(err)
+	}
+}
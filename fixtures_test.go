@@ -0,0 +1,56 @@
+package lavago
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Guild of the players in the fixtures.
+const fixtureGuildID = "817327181659111454"
+
+// Payload captured from Lavalink, stored as testdata/<version>/<name>.json.
+func fixture(tb testing.TB, version, name string) []byte {
+	tb.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", version, name+".json"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return bytes.TrimSpace(data)
+}
+
+// Every fixture of version by name.
+func fixtures(tb testing.TB, version string) map[string][]byte {
+	tb.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", version, "*.json"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	all := map[string][]byte{}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		all[name] = fixture(tb, version, name)
+	}
+	return all
+}
+
+// Node that never reaches a server, with a playing player for the fixtures' guild.
+func fixtureNode(tb testing.TB, codec Codec) *Node {
+	tb.Helper()
+	cfg := NewConfig()
+	cfg.Endpoints = []string{"127.0.0.1:1"}
+	cfg.Codec = codec
+	n, err := NewNode(cfg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	n.ErrorReceived = func(ErrorEvent) {}
+	p := NewPlayer(n.socket, fixtureGuildID)
+	p.node = n
+	p.State = PlayerStatePlaying
+	p.Track = &Track{Track: "QAAAjQIAJVJpY2sgQXN0bGV5IC0gTmV2ZXIgR29ubmEgR2l2ZSBZb3UgVXAADlJpY2tBc3RsZXlWRVZPAAAAAAADPCAAC2RRdzR3OVdnWGNRAAEAK2h0dHBzOi8vd3d3LnlvdXR1YmUuY29tL3dhdGNoP3Y9ZFF3NHc5V2dYY1EAB3lvdXR1YmUAAAAAAAAAAA=="}
+	n.players.Store(fixtureGuildID, p)
+	return n
+}
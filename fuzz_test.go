@@ -0,0 +1,68 @@
+package lavago
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func FuzzSocketDataReceived(f *testing.F) {
+	for _, version := range []string{"v3", "v4"} {
+		for name, data := range fixtures(f, version) {
+			if strings.HasPrefix(name, "load") || name == "decodeTrack" || name == "player" {
+				continue
+			}
+			f.Add(data, version == "v4")
+		}
+	}
+	f.Add([]byte{}, false)
+	f.Add([]byte("null"), false)
+	f.Add([]byte(`{"op":"event","type":"TrackEndEvent","guildId":"817327181659111454"}`), true)
+	f.Add([]byte(`{"op":"playerUpdate","guildId":"817327181659111454","state":{"position":"60000"}}`), false)
+	f.Fuzz(func(t *testing.T, data []byte, v4 bool) {
+		var codec Codec = V3Codec{}
+		if v4 {
+			codec = V4Codec{}
+		}
+		fixtureNode(t, codec).socketDataReceived(data)
+	})
+}
+
+func FuzzSearchResult(f *testing.F) {
+	for _, version := range []string{"v3", "v4"} {
+		for name, data := range fixtures(f, version) {
+			if strings.HasPrefix(name, "load") {
+				f.Add(data)
+			}
+		}
+	}
+	f.Add([]byte(`{"loadType":"search","data":{}}`))
+	f.Add([]byte(`{"loadType":"playlist","data":[]}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sr := &SearchResult{}
+		if json.Unmarshal(data, sr) != nil {
+			return
+		}
+		// Paged like SearchWithOptions.
+		raw := searchResultJSON{}
+		if json.Unmarshal(data, &raw) != nil || raw.normalize() != nil {
+			t.Fatalf("SearchResult decoded but its raw form didn't: %s", data)
+		}
+		page := &SearchResult{Status: raw.Status, limit: 1}
+		if page.decodePage(raw.Tracks) != nil {
+			return
+		}
+		n := len(page.Tracks)
+		for page.HasNextPage() {
+			next, err := page.NextPage()
+			if err != nil {
+				return
+			}
+			n += len(next.Tracks)
+			page = next
+		}
+		if n != len(sr.Tracks) {
+			t.Fatalf("pages returned %d tracks, want %d", n, len(sr.Tracks))
+		}
+	})
+}
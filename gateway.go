@@ -0,0 +1,85 @@
+package lavago
+
+// Voice state change received from Discord's gateway.
+type VoiceStateUpdate struct {
+	// ID of the bot user on the shard that received the update.
+	ShardUserID string
+	// ID of the user whose voice state changed.
+	UserID  string
+	GuildID string
+	// Empty when the user left voice.
+	ChannelID string
+	SessionID string
+}
+
+// Voice server assignment received from Discord's gateway.
+type VoiceServerUpdate struct {
+	GuildID string
+	// Empty while Discord is allocating a voice server.
+	Endpoint string
+	Token    string
+}
+
+// Connects lavago to a Discord gateway, whether that's a Discord library or a custom gateway proxy.
+type GatewayBridge interface {
+	// Asks Discord to move the bot into the voice channel, or out of voice if channelID is empty.
+	SendVoiceStateUpdate(guildID, channelID string, deaf bool) error
+	// Registers the handler called for every voice state update.
+	OnVoiceState(handler func(VoiceStateUpdate))
+	// Registers the handler called for every voice server update.
+	OnVoiceServer(handler func(VoiceServerUpdate))
+}
+
+// Implemented by bridges that cache voice states, lets `GuildHandle.PlayQuery` find the
+// requester's channel.
+type VoiceStateLookup interface {
+	// Voice channel the user is in, empty if they aren't in voice.
+	UserVoiceChannel(guildID, userID string) (string, error)
+}
+
+// Joins voice channels through the bridge and forwards its voice events to the node.
+func (n *Node) UseGateway(b GatewayBridge) {
+	connectVia(n, b)
+	b.OnVoiceState(func(u VoiceStateUpdate) {
+		n.OnVoiceStateUpdateChannel(u.ShardUserID, u.UserID, u.GuildID, u.ChannelID, u.SessionID)
+	})
+	b.OnVoiceServer(func(u VoiceServerUpdate) {
+		n.OnVoiceServerUpdate(u.GuildID, u.Endpoint, u.Token)
+	})
+}
+
+// Joins voice channels through the bridge on every node and forwards voice events to the
+// node holding the guild's player. Nodes added later are wired up by `AddNode`.
+func (pl *Pool) UseGateway(b GatewayBridge) {
+	pl.mu.Lock()
+	pl.gateway = b
+	for _, n := range pl.nodes {
+		connectVia(n, b)
+	}
+	pl.mu.Unlock()
+	b.OnVoiceState(func(u VoiceStateUpdate) {
+		if n := pl.nodeOf(u.GuildID); n != nil {
+			n.OnVoiceStateUpdateChannel(u.ShardUserID, u.UserID, u.GuildID, u.ChannelID, u.SessionID)
+		}
+	})
+	b.OnVoiceServer(func(u VoiceServerUpdate) {
+		if n := pl.nodeOf(u.GuildID); n != nil {
+			n.OnVoiceServerUpdate(u.GuildID, u.Endpoint, u.Token)
+		}
+	})
+}
+
+// Sends the node's voice state updates through b and looks up voice states with it if it can.
+func connectVia(n *Node, b GatewayBridge) {
+	n.ConnectVoice = b.SendVoiceStateUpdate
+	n.DisconnectVoice = disconnectVia(b)
+	if l, ok := b.(VoiceStateLookup); ok {
+		n.UserVoiceChannel = l.UserVoiceChannel
+	}
+}
+
+func disconnectVia(b GatewayBridge) func(guildID string) error {
+	return func(guildID string) error {
+		return b.SendVoiceStateUpdate(guildID, "", false)
+	}
+}
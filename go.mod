@@ -1,8 +1,5 @@
 module github.com/nemphi/lavago
 
-go 1.16
+go 1.18
 
-require (
-	github.com/emirpasic/gods v1.12.0
-	github.com/gorilla/websocket v1.4.2
-)
+require github.com/gorilla/websocket v1.4.2
@@ -0,0 +1,304 @@
+package lavago
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Returned by `GuildHandle` methods that need a player when the guild has none.
+var ErrNoPlayer = errors.New("guild has no player")
+
+// Returned when a query loaded no tracks.
+var ErrNoResults = errors.New("query returned no tracks")
+
+// Returned by `GuildHandle.PlayQuery` when the requester isn't in a voice channel.
+var ErrNotInVoice = errors.New("requester isn't in a voice channel")
+
+// Every per-guild operation of a node, so command handlers can hold one value instead of
+// passing the guild ID around. Handles are cheap, the player is looked up on every call.
+type GuildHandle struct {
+	Node    *Node
+	GuildID string
+}
+
+// Handle for the guild's player on this node.
+func (n *Node) Guild(guildID string) *GuildHandle {
+	return &GuildHandle{Node: n, GuildID: guildID}
+}
+
+// The guild's player, nil if it has none.
+func (g *GuildHandle) Player() *Player {
+	return g.Node.GetPlayer(g.GuildID)
+}
+
+func (g *GuildHandle) player() (*Player, error) {
+	p := g.Player()
+	if p == nil {
+		return nil, ErrNoPlayer
+	}
+	return p, nil
+}
+
+// Joins the voice channel, see `Node.JoinOrGet`.
+func (g *GuildHandle) Join(channelID string) (*Player, error) {
+	return g.Node.JoinOrGet(g.GuildID, channelID)
+}
+
+func (g *GuildHandle) Leave() error {
+	return g.Node.Leave(g.GuildID)
+}
+
+// Plays track right away, replacing the current one.
+func (g *GuildHandle) Play(track *Track) error {
+	p, err := g.player()
+	if err != nil {
+		return err
+	}
+	return p.PlayTrack(track)
+}
+
+// Starts the first track if nothing is playing and queues the others. Reports whether a
+// track was started. Stops at the first track the queue rejects.
+func (g *GuildHandle) Enqueue(tracks ...*Track) (started bool, err error) {
+	p, err := g.player()
+	if err != nil {
+		return false, err
+	}
+	started, _, err = enqueue(p, tracks)
+	return started, err
+}
+
+// Like `GuildHandle.Enqueue`, also reporting how many tracks were started or queued.
+func enqueue(p *Player, tracks []*Track) (started bool, n int, err error) {
+	if len(tracks) == 0 {
+		return false, 0, nil
+	}
+	switch p.currentState() {
+	case PlayerStatePlaying, PlayerStatePaused, PlayerStateBuffering:
+	default:
+		if err := p.PlayTrack(tracks[0]); err != nil {
+			return false, 0, err
+		}
+		started = true
+		n++
+	}
+	for _, t := range tracks[n:] {
+		if err := p.Queue.Enqueue(t); err != nil {
+			return started, n, err
+		}
+		n++
+	}
+	return started, n, nil
+}
+
+// Loads query with `Node.Resolve` and enqueues the result, every track of a playlist or the
+// best match of a search, with requester set as their Requester.
+func (g *GuildHandle) SearchAndEnqueue(query, requester string) ([]*Track, error) {
+	_, tracks, err := g.Node.loadQuery(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tracks {
+		t.Requester = requester
+	}
+	_, err = g.Enqueue(tracks...)
+	return tracks, err
+}
+
+// What `GuildHandle.PlayQuery` did with the tracks it loaded.
+type PlayOutcome int
+
+const (
+	// The first track started playing and the others were queued.
+	PlayStarted PlayOutcome = iota
+	// Every track was queued behind the one playing.
+	PlayEnqueued
+)
+
+func (o PlayOutcome) String() string {
+	switch o {
+	case PlayStarted:
+		return "Started"
+	case PlayEnqueued:
+		return "Enqueued"
+	}
+	return "Unknown"
+}
+
+// Result of `GuildHandle.PlayQuery`, enough to write the reply of a play command.
+type PlayResult struct {
+	Outcome PlayOutcome
+	// Tracks started or queued, all of a playlist or the best match of a search.
+	Tracks []*Track
+	// Name of the loaded playlist, empty for single tracks and searches.
+	Playlist string
+	// Queue position of the first queued track, starting at 1. Zero if only a started track was loaded.
+	Position int
+}
+
+// The 80% case of a play command: loads query like `Node.Resolve`, joins the requester's voice
+// channel and starts or enqueues the result, with requester set as the tracks' Requester.
+// The requester's channel is found with `Node.UserVoiceChannel`, so a bridge implementing
+// `VoiceStateLookup` or the hook must be set. A player in another channel is handled like
+// `Node.JoinOrGet` does. If the queue rejects a track, the tracks before it stay queued and
+// the result reports them along with the error.
+func (g *GuildHandle) PlayQuery(ctx context.Context, query, requester string) (*PlayResult, error) {
+	if g.Node.UserVoiceChannel == nil {
+		return nil, errors.New("can't find the requester's voice channel, UserVoiceChannel isn't set")
+	}
+	channelID, err := g.Node.UserVoiceChannel(g.GuildID, requester)
+	if err != nil {
+		return nil, err
+	}
+	if channelID == "" {
+		return nil, ErrNotInVoice
+	}
+	sr, tracks, err := g.Node.loadQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	p, err := g.Join(channelID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tracks {
+		t.Requester = requester
+	}
+	res := &PlayResult{Outcome: PlayEnqueued, Position: p.Queue.Len() + 1}
+	if sr.Status == PlaylistLoadedSearchStatus {
+		res.Playlist = sr.Playlist.Name
+	}
+	started, n, err := enqueue(p, tracks)
+	if started {
+		res.Outcome = PlayStarted
+		if n == 1 {
+			res.Position = 0
+		}
+	}
+	res.Tracks = tracks[:n]
+	return res, err
+}
+
+// Load result and tracks to play for query, only the first match for searches.
+func (n *Node) loadQuery(ctx context.Context, query string) (*SearchResult, []*Track, error) {
+	sr, err := n.resolveContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	tracks, err := loadedTracks(sr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sr, tracks, nil
+}
+
+// Tracks to play from a load result, the first one of searches and every track of playlists.
+func loadedTracks(sr *SearchResult) ([]*Track, error) {
+	switch sr.Status {
+	case LoadFailedSearchStatus:
+		return nil, sr.Exception
+	case SearchResultSearchStatus, TrackLoadedSearchStatus:
+		if len(sr.Tracks) > 0 {
+			return sr.Tracks[:1], nil
+		}
+	case PlaylistLoadedSearchStatus:
+		if len(sr.Tracks) > 0 {
+			return sr.Tracks, nil
+		}
+	}
+	return nil, ErrNoResults
+}
+
+// Skips to the next queued track, see `Player.SkipNow`.
+func (g *GuildHandle) Skip() (skipped *Track, current *Track, err error) {
+	p, err := g.player()
+	if err != nil {
+		return nil, nil, err
+	}
+	return p.SkipNow()
+}
+
+func (g *GuildHandle) Stop() error {
+	p, err := g.player()
+	if err != nil {
+		return err
+	}
+	return p.Stop()
+}
+
+func (g *GuildHandle) Pause() error {
+	p, err := g.player()
+	if err != nil {
+		return err
+	}
+	return p.Pause()
+}
+
+func (g *GuildHandle) Resume() error {
+	p, err := g.player()
+	if err != nil {
+		return err
+	}
+	return p.Resume()
+}
+
+func (g *GuildHandle) Seek(position time.Duration) error {
+	p, err := g.player()
+	if err != nil {
+		return err
+	}
+	return p.Seek(position)
+}
+
+func (g *GuildHandle) SetVolume(volume int) error {
+	p, err := g.player()
+	if err != nil {
+		return err
+	}
+	return p.UpdateVolume(volume)
+}
+
+// Filters last applied, empty if the guild has no player.
+func (g *GuildHandle) Filters() Filters {
+	p := g.Player()
+	if p == nil {
+		return Filters{}
+	}
+	return p.Filters()
+}
+
+func (g *GuildHandle) SetFilters(f Filters) error {
+	p, err := g.player()
+	if err != nil {
+		return err
+	}
+	return p.SetFilters(f)
+}
+
+// Changes the filters with fn, see `Player.UpdateFilters`.
+func (g *GuildHandle) UpdateFilters(fn func(*Filters)) error {
+	p, err := g.player()
+	if err != nil {
+		return err
+	}
+	return p.UpdateFilters(fn)
+}
+
+// The player's queue, nil if the guild has no player.
+func (g *GuildHandle) Queue() *TrackQueue {
+	p := g.Player()
+	if p == nil {
+		return nil
+	}
+	return p.Queue
+}
+
+// Nil if the guild has no player or nothing is playing.
+func (g *GuildHandle) NowPlaying() *NowPlaying {
+	p := g.Player()
+	if p == nil {
+		return nil
+	}
+	return p.NowPlaying()
+}
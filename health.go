@@ -0,0 +1,109 @@
+package lavago
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Outcome of a REST probe, see `Node.HealthCheck`.
+type Health struct {
+	// Whether Lavalink answered at all.
+	Reachable bool
+	// Whether Lavalink accepted the password.
+	Authorized bool
+	// Server version, empty if unknown.
+	Version string
+	// Round trip time of the probe.
+	Latency   time.Duration
+	CheckedAt time.Time
+	// Why the probe failed, nil if healthy.
+	Err error
+}
+
+// Whether the probe succeeded.
+func (h Health) Healthy() bool {
+	return h.Reachable && h.Authorized && h.Err == nil
+}
+
+// Information about a failed health check of a node that was healthy before.
+type NodeUnhealthyEvent struct {
+	// Node for which this event fired.
+	Node   *Node
+	Health Health
+}
+
+// Probes the node with a cheap REST request to /version. Servers older than Lavalink 3.4
+// answer 404 and count as healthy with an unknown version.
+func (n *Node) HealthCheck(ctx context.Context) Health {
+	h := Health{CheckedAt: time.Now()}
+	version, err := n.Version(ctx)
+	h.Latency = time.Since(h.CheckedAt)
+	h.Version = version
+	var se *statusError
+	switch {
+	case err == nil, errors.Is(err, ErrVersionUnknown):
+		h.Reachable, h.Authorized = true, true
+	case errors.As(err, &se):
+		h.Reachable = true
+		h.Authorized = se.StatusCode != http.StatusUnauthorized && se.StatusCode != http.StatusForbidden
+		h.Err = err
+	default:
+		h.Err = err
+	}
+	return h
+}
+
+// Result of the last periodic health check, see `Config.HealthCheckInterval`. Zero if none ran yet.
+func (n *Node) Health() Health {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.health
+}
+
+// Whether the last periodic health check failed. Such nodes don't get new players in a `Pool`.
+func (n *Node) Unhealthy() bool {
+	h := n.Health()
+	return !h.CheckedAt.IsZero() && !h.Healthy()
+}
+
+// Runs HealthCheck every `Config.HealthCheckInterval` until ctx is done, firing NodeUnhealthy
+// whenever a check fails after a successful one. Stops the checks started before.
+func (n *Node) startHealthChecks(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	n.mu.Lock()
+	if n.stopHealth != nil {
+		n.stopHealth()
+	}
+	n.stopHealth = cancel
+	n.mu.Unlock()
+	interval := n.config().HealthCheckInterval
+	if interval <= 0 {
+		return
+	}
+	n.socket.spawn(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			checkCtx, cancel := context.WithTimeout(ctx, interval)
+			h := n.HealthCheck(checkCtx)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			n.mu.Lock()
+			wasHealthy := n.health.CheckedAt.IsZero() || n.health.Healthy()
+			n.health = h
+			n.mu.Unlock()
+			if wasHealthy && !h.Healthy() && n.NodeUnhealthy != nil {
+				n.NodeUnhealthy(NodeUnhealthyEvent{Node: n, Health: h})
+			}
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package lavago
+
+import (
+	"context"
+	"strings"
+)
+
+// Lavalink's /info response, available since Lavalink 3.7.
+type ServerInfo struct {
+	Version struct {
+		Semver string `json:"semver"`
+		Major  int    `json:"major"`
+		Minor  int    `json:"minor"`
+		Patch  int    `json:"patch"`
+	} `json:"version"`
+	// Milliseconds since epoch of the server's build.
+	BuildTime  int64  `json:"buildTime"`
+	JVM        string `json:"jvm"`
+	Lavaplayer string `json:"lavaplayer"`
+	// Enabled audio sources, i.e. "youtube" or "deezer" when LavaSrc provides it.
+	SourceManagers []string     `json:"sourceManagers"`
+	Filters        []string     `json:"filters"`
+	Plugins        []PluginInfo `json:"plugins"`
+}
+
+// Plugin loaded by Lavalink.
+type PluginInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Fetches the server's version, sources and plugins.
+func (n *Node) Info(ctx context.Context) (*ServerInfo, error) {
+	urlPath := "/v3/info"
+	if n.APIVersion() >= 4 {
+		urlPath = "/v4/info"
+	}
+	info := &ServerInfo{}
+	err := n.getContext(ctx, urlPath, info)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Whether the audio source with the given name is enabled, ignoring case.
+func (si *ServerInfo) HasSource(name string) bool {
+	for _, s := range si.SourceManagers {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the plugin with the given name is loaded, ignoring case.
+func (si *ServerInfo) HasPlugin(name string) bool {
+	for _, p := range si.Plugins {
+		if strings.EqualFold(p.Name, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package lavago
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Stand-in for a Lavalink server that accepts the websocket and records the ops sent to it.
+type fakeLavalink struct {
+	*httptest.Server
+	ops chan map[string]interface{}
+
+	mu    sync.Mutex
+	conns []*websocket.Conn
+	// Answers further handshakes with 503 once set.
+	refuse bool
+	// Reported by /version when set, which is a 404 otherwise like on servers before 3.4.
+	version string
+	// Requests made to /version.
+	versionRequests int
+}
+
+func newFakeLavalink(t *testing.T) *fakeLavalink {
+	t.Helper()
+	f := &fakeLavalink{ops: make(chan map[string]interface{}, 64)}
+	upgrader := websocket.Upgrader{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !websocket.IsWebSocketUpgrade(r) {
+			f.mu.Lock()
+			version := f.version
+			if r.URL.Path == "/version" {
+				f.versionRequests++
+			}
+			f.mu.Unlock()
+			if r.URL.Path == "/version" && version != "" {
+				w.Write([]byte(version))
+				return
+			}
+			http.NotFound(w, r)
+			return
+		}
+		f.mu.Lock()
+		refuse := f.refuse
+		f.mu.Unlock()
+		if refuse {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, http.Header{"Lavalink-Api-Version": {"3"}})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		f.mu.Lock()
+		f.conns = append(f.conns, conn)
+		f.mu.Unlock()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var op map[string]interface{}
+			if json.Unmarshal(data, &op) == nil {
+				select {
+				case f.ops <- op:
+				default:
+				}
+			}
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// Closes every open connection with the given close code, refusing new ones if refuse is set.
+func (f *fakeLavalink) drop(code int, refuse bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.refuse = refuse
+	for _, conn := range f.conns {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""), time.Now().Add(time.Second))
+		conn.Close()
+	}
+	f.conns = nil
+}
+
+// Config pointing at the fake server.
+func (f *fakeLavalink) config(t *testing.T) *Config {
+	t.Helper()
+	host, port, err := net.SplitHostPort(f.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewConfig()
+	cfg.Hostname = host
+	cfg.Port, _ = strconv.Atoi(port)
+	cfg.ReconnectAttempts = 0
+	return cfg
+}
+
+// Node connected to the fake server, closed when the test ends.
+func (f *fakeLavalink) connect(t *testing.T, cfg *Config) *Node {
+	t.Helper()
+	n, err := NewNode(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Connect("1", "1"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		n.Close()
+		waitReturns(t, n)
+	})
+	return n
+}
+
+func (f *fakeLavalink) node(t *testing.T) *Node {
+	return f.connect(t, f.config(t))
+}
+
+// Idle player for guild "1".
+func join(t *testing.T, n *Node) *Player {
+	t.Helper()
+	p, err := n.Join("1", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.setState(PlayerStateStopped)
+	return p
+}
+
+func (f *fakeLavalink) player(t *testing.T) (*Node, *Player) {
+	n := f.node(t)
+	return n, join(t, n)
+}
+
+// Waits for the next op matching the predicate, skipping others.
+func (f *fakeLavalink) next(t *testing.T, match func(op map[string]interface{}) bool) map[string]interface{} {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case op := <-f.ops:
+			if match(op) {
+				return op
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for op")
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package lavago
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Lavaplayer's message header flag for versioned track info.
+const trackInfoVersioned = 1
+
+// Encodes the track in lavaplayer's binary track format, the same string Lavalink
+// uses to identify tracks. Tracks that already carry their encoded form return it as is.
+//
+// Tracks are written as version 2, understood by every Lavalink release, unless they
+// carry an artwork url or ISRC, which need version 3 (Lavalink v4). Source specific
+// data, like the container probe of http tracks, can't be derived client-side and is omitted.
+func EncodeTrack(t *Track) string {
+	if t.Track != "" {
+		return t.Track
+	}
+	version := byte(2)
+	if t.Info.Artwork != "" || t.Info.ISRC != "" {
+		version = 3
+	}
+	body := &bytes.Buffer{}
+	body.WriteByte(version)
+	writeUTF(body, t.Info.Title)
+	writeUTF(body, t.Info.Author)
+	binary.Write(body, binary.BigEndian, t.Info.Length.Milliseconds())
+	writeUTF(body, t.Info.Identifier)
+	writeBool(body, t.Info.IsStream)
+	writeNullableUTF(body, t.Info.URL)
+	if version >= 3 {
+		writeNullableUTF(body, t.Info.Artwork)
+		writeNullableUTF(body, t.Info.ISRC)
+	}
+	writeUTF(body, t.Info.SourceName)
+	binary.Write(body, binary.BigEndian, t.Info.Position.Milliseconds())
+
+	msg := &bytes.Buffer{}
+	binary.Write(msg, binary.BigEndian, uint32(trackInfoVersioned<<30|body.Len()))
+	msg.Write(body.Bytes())
+	return base64.StdEncoding.EncodeToString(msg.Bytes())
+}
+
+// Decodes a track encoded in lavaplayer's binary track format without asking Lavalink.
+func DecodeTrackString(encoded string) (*Track, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 {
+		return nil, errors.New("encoded track is too short")
+	}
+	header := binary.BigEndian.Uint32(data)
+	size := int(header & 0x3FFFFFFF)
+	body := data[4:]
+	if size > len(body) {
+		return nil, errors.New("encoded track is truncated")
+	}
+	body = body[:size]
+	r := bytes.NewReader(body)
+
+	version := byte(1)
+	if header>>30&trackInfoVersioned != 0 {
+		version, err = r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if version < 1 || version > 3 {
+		return nil, fmt.Errorf("unsupported encoded track version %v", version)
+	}
+
+	t := &Track{Track: encoded}
+	var length int64
+	if t.Info.Title, err = readUTF(r); err != nil {
+		return nil, err
+	}
+	if t.Info.Author, err = readUTF(r); err != nil {
+		return nil, err
+	}
+	if err = binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	t.Info.Length = time.Duration(length) * time.Millisecond
+	if t.Info.Identifier, err = readUTF(r); err != nil {
+		return nil, err
+	}
+	if t.Info.IsStream, err = readBool(r); err != nil {
+		return nil, err
+	}
+	if version >= 2 {
+		if t.Info.URL, err = readNullableUTF(r); err != nil {
+			return nil, err
+		}
+	}
+	if version >= 3 {
+		if t.Info.Artwork, err = readNullableUTF(r); err != nil {
+			return nil, err
+		}
+		if t.Info.ISRC, err = readNullableUTF(r); err != nil {
+			return nil, err
+		}
+	}
+	if t.Info.SourceName, err = readUTF(r); err != nil {
+		return nil, err
+	}
+	// Source specific data sits between the source name and the position, which is always last.
+	if r.Len() < 8 {
+		return nil, errors.New("encoded track is truncated")
+	}
+	t.Info.Position = time.Duration(int64(binary.BigEndian.Uint64(body[len(body)-8:]))) * time.Millisecond
+	t.Info.CanSeek = !t.Info.IsStream
+	return t, nil
+}
+
+func writeBool(w *bytes.Buffer, b bool) {
+	if b {
+		w.WriteByte(1)
+	} else {
+		w.WriteByte(0)
+	}
+}
+
+func readBool(r *bytes.Reader) (bool, error) {
+	b, err := r.ReadByte()
+	return b != 0, err
+}
+
+func writeNullableUTF(w *bytes.Buffer, s string) {
+	writeBool(w, s != "")
+	if s != "" {
+		writeUTF(w, s)
+	}
+}
+
+func readNullableUTF(r *bytes.Reader) (string, error) {
+	present, err := readBool(r)
+	if err != nil || !present {
+		return "", err
+	}
+	return readUTF(r)
+}
+
+// Writes s in Java's modified UTF-8 as used by DataOutput.writeUTF, truncating it to the format's 65535 byte limit.
+func writeUTF(w *bytes.Buffer, s string) {
+	buf := make([]byte, 0, len(s))
+	for _, r := range s {
+		var units []uint16
+		if r >= 0x10000 {
+			r1, r2 := utf16.EncodeRune(r)
+			units = []uint16{uint16(r1), uint16(r2)}
+		} else {
+			units = []uint16{uint16(r)}
+		}
+		var enc []byte
+		for _, c := range units {
+			switch {
+			case c != 0 && c < 0x80:
+				enc = append(enc, byte(c))
+			case c < 0x800:
+				enc = append(enc, byte(0xC0|c>>6), byte(0x80|c&0x3F))
+			default:
+				enc = append(enc, byte(0xE0|c>>12), byte(0x80|c>>6&0x3F), byte(0x80|c&0x3F))
+			}
+		}
+		if len(buf)+len(enc) > 0xFFFF {
+			break
+		}
+		buf = append(buf, enc...)
+	}
+	binary.Write(w, binary.BigEndian, uint16(len(buf)))
+	w.Write(buf)
+}
+
+// Reads a string written by Java's DataOutput.writeUTF.
+func readUTF(r *bytes.Reader) (string, error) {
+	var n uint16
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	units := make([]uint16, 0, n)
+	for i := 0; i < len(buf); {
+		b := buf[i]
+		switch {
+		case b < 0x80:
+			units = append(units, uint16(b))
+			i++
+		case b&0xE0 == 0xC0 && i+1 < len(buf):
+			units = append(units, uint16(b&0x1F)<<6|uint16(buf[i+1]&0x3F))
+			i += 2
+		case b&0xF0 == 0xE0 && i+2 < len(buf):
+			units = append(units, uint16(b&0x0F)<<12|uint16(buf[i+1]&0x3F)<<6|uint16(buf[i+2]&0x3F))
+			i += 3
+		default:
+			return "", errors.New("malformed modified UTF-8 string")
+		}
+	}
+	runes := utf16.Decode(units)
+	out := make([]byte, 0, len(runes))
+	for _, r := range runes {
+		out = utf8.AppendRune(out, r)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,80 @@
+package lavago
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Fails the test if Wait doesn't return in time.
+func waitReturns(t *testing.T, n *Node) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		n.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait didn't return")
+	}
+}
+
+func TestWaitAfterClose(t *testing.T) {
+	n := newFakeLavalink(t).node(t)
+	if err := n.Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitReturns(t, n)
+	if st := n.State(); st != NodeStateDisconnected {
+		t.Errorf("state %v after Close, want %v", st, NodeStateDisconnected)
+	}
+}
+
+func TestCloseFailsQueuedSends(t *testing.T) {
+	n := newFakeLavalink(t).node(t)
+	n.Close()
+	if err := n.socket.Send([]byte(`{"op":"stop","guildId":"1"}`)); err == nil {
+		t.Error("Send on a closed node succeeded")
+	}
+	waitReturns(t, n)
+}
+
+func TestWaitAfterFatalClose(t *testing.T) {
+	f := newFakeLavalink(t)
+	cfg := f.config(t)
+	cfg.ReconnectAttempts = 3
+	// Health checks run until the lifecycle ends.
+	cfg.HealthCheckInterval = time.Hour
+	n := f.connect(t, cfg)
+	f.drop(websocket.ClosePolicyViolation, false)
+	waitReturns(t, n)
+	if st := n.State(); st != NodeStateDisconnected {
+		t.Errorf("state %v after a fatal close, want %v", st, NodeStateDisconnected)
+	}
+}
+
+func TestWaitAfterReconnectAttemptsRanOut(t *testing.T) {
+	f := newFakeLavalink(t)
+	cfg := f.config(t)
+	cfg.ReconnectAttempts = 2
+	cfg.Backoff = ConstantBackoff{Interval: time.Millisecond}
+	cfg.HealthCheckInterval = time.Hour
+	n := f.connect(t, cfg)
+	join(t, n)
+	f.drop(websocket.CloseInternalServerErr, true)
+	waitReturns(t, n)
+	if st := n.State(); st != NodeStateDisconnected {
+		t.Errorf("state %v after running out of reconnect attempts, want %v", st, NodeStateDisconnected)
+	}
+	if n.HasPlayer("1") {
+		t.Error("player kept after the node gave up")
+	}
+	// The node starts over once Lavalink is back.
+	f.drop(websocket.CloseNormalClosure, false)
+	if err := n.Connect("1", "1"); err != nil {
+		t.Fatal(err)
+	}
+}
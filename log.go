@@ -0,0 +1,25 @@
+package lavago
+
+// Severities for `Config.LogSeverity`, from most to least severe.
+const (
+	LogCritical = iota
+	LogError
+	LogWarning
+	LogInfo
+	LogVerbose
+	LogDebug
+)
+
+// Receives log messages, i.e. a *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Logs through `Config.Logger` if severity is within `Config.LogSeverity`.
+func (n *Node) logf(severity int, format string, v ...interface{}) {
+	cfg := n.config()
+	if cfg.Logger == nil || severity > cfg.LogSeverity {
+		return
+	}
+	cfg.Logger.Printf("lavago: node %s: "+format, append([]interface{}{n.ID()}, v...)...)
+}
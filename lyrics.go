@@ -0,0 +1,83 @@
+package lavago
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Name the LavaLyrics plugin is listed under in `ServerInfo.Plugins`.
+const LyricsPluginName = "lavalyrics-plugin"
+
+// Returned by `Node.TrackLyrics` when the plugin found no lyrics.
+var ErrNoLyrics = errors.New("no lyrics found")
+
+// Lyrics found by the LavaLyrics plugin.
+type PluginLyrics struct {
+	// Source the lyrics came from, i.e. "deezer" or "youtube".
+	SourceName string
+	Provider   string
+	// Plain lyrics, empty if the source only has synced lines.
+	Text string
+	// Time-synced lines in order, nil if the source has none.
+	Lines []PluginLyricsLine
+}
+
+// Line of time-synced lyrics.
+type PluginLyricsLine struct {
+	// Position in the track the line starts at.
+	Start    time.Duration
+	Duration time.Duration
+	Text     string
+}
+
+type pluginLyricsJSON struct {
+	SourceName string `json:"sourceName"`
+	Provider   string `json:"provider"`
+	Text       string `json:"text"`
+	Lines      []struct {
+		Timestamp millis `json:"timestamp"`
+		Duration  millis `json:"duration"`
+		Line      string `json:"line"`
+	} `json:"lines"`
+}
+
+// Asks the LavaLyrics plugin for the encoded track's lyrics, Lavalink v4 only. Returns
+// ErrNoLyrics if it found none, check `ServerInfo.HasPlugin` for whether the server has it.
+func (n *Node) TrackLyrics(ctx context.Context, encoded string) (*PluginLyrics, error) {
+	if encoded == "" {
+		return nil, errors.New("can't fetch lyrics of empty track")
+	}
+	if n.APIVersion() < 4 {
+		return nil, errors.New("lyrics need Lavalink v4")
+	}
+	res, err := n.requestContext(ctx, "GET", "/v4/lyrics?"+url.Values{"track": {encoded}}.Encode(), nil)
+	var se *statusError
+	if errors.As(err, &se) && se.StatusCode == http.StatusNotFound {
+		return nil, ErrNoLyrics
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNoContent {
+		return nil, ErrNoLyrics
+	}
+	raw := pluginLyricsJSON{}
+	err = json.NewDecoder(res.Body).Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+	l := &PluginLyrics{SourceName: raw.SourceName, Provider: raw.Provider, Text: raw.Text}
+	for _, line := range raw.Lines {
+		l.Lines = append(l.Lines, PluginLyricsLine{
+			Start:    time.Duration(line.Timestamp),
+			Duration: time.Duration(line.Duration),
+			Text:     line.Line,
+		})
+	}
+	return l, nil
+}
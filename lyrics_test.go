@@ -0,0 +1,31 @@
+package lavago
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTrackLyrics(t *testing.T) {
+	const encoded = "QAAAjQIAJVJpY2sgQXN0bGV5IC0gTmV2ZXIgR29ubmEgR2l2ZSBZb3UgVXAADlJpY2tBc3RsZXlWRVZPAAAAAAADPCAAC2RRdzR3OVdnWGNRAAEAK2h0dHBzOi8vd3d3LnlvdXR1YmUuY29tL3dhdGNoP3Y9ZFF3NHc5V2dYY1EAB3lvdXR1YmUAAAAAAAAAAA=="
+	n := restNode(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v4/lyrics" || r.URL.Query().Get("track") != encoded {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`{"sourceName":"deezer","provider":"LyricFind","text":null,"lines":[{"timestamp":18000,"duration":3500,"line":"We're no strangers to love","plugin":{}}],"plugin":{}}`))
+	})
+	n.apiVersion = 4
+	l, err := n.TrackLyrics(context.Background(), encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PluginLyricsLine{Start: 18 * time.Second, Duration: 3500 * time.Millisecond, Text: "We're no strangers to love"}
+	if l.SourceName != "deezer" || l.Provider != "LyricFind" || len(l.Lines) != 1 || l.Lines[0] != want {
+		t.Errorf("got %+v, want one line %+v", l, want)
+	}
+	if _, err := n.TrackLyrics(context.Background(), "QAAA"); err != ErrNoLyrics {
+		t.Errorf("err = %v, want %v", err, ErrNoLyrics)
+	}
+}
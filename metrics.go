@@ -0,0 +1,101 @@
+package lavago
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Traffic and dispatch counters of a node's websocket, see `Node.Metrics`.
+type Metrics struct {
+	MessagesSent     int64
+	MessagesReceived int64
+	BytesSent        int64
+	BytesReceived    int64
+	// Messages waiting for the writer.
+	SendQueueDepth int
+	// Received messages whose handlers haven't returned yet.
+	InFlightDispatches int64
+	// Moving average of the time between reading a message and starting its handler.
+	DispatchLag time.Duration
+}
+
+// Fired when more received messages than `Config.SlowConsumerThreshold` are being handled at
+// once, meaning event handlers are slower than events arrive and goroutines pile up.
+// Fires again once the backlog dropped to half the threshold and exceeded it again.
+type SlowConsumerEvent struct {
+	// Node for which this event fired.
+	Node               *Node
+	InFlightDispatches int64
+	DispatchLag        time.Duration
+}
+
+// Updated atomically, the int64 fields come first to keep them aligned on 32 bit platforms.
+type socketMetrics struct {
+	messagesSent     int64
+	messagesReceived int64
+	bytesSent        int64
+	bytesReceived    int64
+	inFlight         int64
+	// Nanoseconds.
+	dispatchLag int64
+	// 1 while the slow consumer alert is raised.
+	slow int32
+}
+
+func (m *socketMetrics) sent(n int) {
+	atomic.AddInt64(&m.messagesSent, 1)
+	atomic.AddInt64(&m.bytesSent, int64(n))
+}
+
+func (m *socketMetrics) received(n int) {
+	atomic.AddInt64(&m.messagesReceived, 1)
+	atomic.AddInt64(&m.bytesReceived, int64(n))
+}
+
+// Records a handler starting after lag, weighing it by 1/8 into the average.
+func (m *socketMetrics) dispatched(lag time.Duration) {
+	for {
+		old := atomic.LoadInt64(&m.dispatchLag)
+		avg := old + (int64(lag)-old)/8
+		if atomic.CompareAndSwapInt64(&m.dispatchLag, old, avg) {
+			return
+		}
+	}
+}
+
+// Counts a dispatch starting, reporting whether the backlog just crossed threshold.
+func (m *socketMetrics) enter(threshold int) bool {
+	inFlight := atomic.AddInt64(&m.inFlight, 1)
+	return threshold > 0 && inFlight > int64(threshold) && atomic.CompareAndSwapInt32(&m.slow, 0, 1)
+}
+
+// Counts a dispatch returning, clearing the alert once the backlog dropped to half threshold.
+func (m *socketMetrics) leave(threshold int) {
+	inFlight := atomic.AddInt64(&m.inFlight, -1)
+	// Rounded down, a threshold of 1 clears once nothing is in flight.
+	if inFlight <= int64(threshold/2) {
+		atomic.StoreInt32(&m.slow, 0)
+	}
+}
+
+// Counters of the node's websocket since it was created, across reconnects.
+func (n *Node) Metrics() Metrics {
+	m := n.socket.metrics
+	return Metrics{
+		MessagesSent:       atomic.LoadInt64(&m.messagesSent),
+		MessagesReceived:   atomic.LoadInt64(&m.messagesReceived),
+		BytesSent:          atomic.LoadInt64(&m.bytesSent),
+		BytesReceived:      atomic.LoadInt64(&m.bytesReceived),
+		SendQueueDepth:     len(n.socket.sendChan),
+		InFlightDispatches: atomic.LoadInt64(&m.inFlight),
+		DispatchLag:        time.Duration(atomic.LoadInt64(&m.dispatchLag)),
+	}
+}
+
+func (n *Node) socketSlowConsumer() {
+	if n.SlowConsumer == nil {
+		return
+	}
+	m := n.Metrics()
+	n.SlowConsumer(SlowConsumerEvent{Node: n, InFlightDispatches: m.InFlightDispatches, DispatchLag: m.DispatchLag})
+}
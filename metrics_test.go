@@ -0,0 +1,23 @@
+package lavago
+
+import "testing"
+
+func TestSlowConsumerAlert(t *testing.T) {
+	for _, threshold := range []int{1, 2, 5} {
+		m := &socketMetrics{}
+		fired := 0
+		for round := 0; round < 2; round++ {
+			for i := 0; i <= threshold; i++ {
+				if m.enter(threshold) {
+					fired++
+				}
+			}
+			for i := 0; i <= threshold; i++ {
+				m.leave(threshold)
+			}
+		}
+		if fired != 2 {
+			t.Errorf("threshold %d: fired %d times over two backlogs, want 2", threshold, fired)
+		}
+	}
+}
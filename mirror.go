@@ -0,0 +1,116 @@
+package lavago
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Returned by `Node.Mirror` when no playable track matches.
+var ErrNoMirror = errors.New("no matching track found")
+
+// Finds a playable track on YouTube Music for a track known from another service, i.e. Spotify
+// metadata when the server has no plugin for it. Searches by ISRC first when it's known and then
+// by author and title, taking the first result `Track.Matches` accepts.
+func (n *Node) Mirror(ctx context.Context, info TrackInfo) (*Track, error) {
+	if info.Title == "" {
+		return nil, errors.New("can't mirror track without a title")
+	}
+	want := &Track{Info: info}
+	if info.ISRC != "" {
+		tracks, err := n.mirrorSearch(ctx, `"`+info.ISRC+`"`)
+		if err != nil {
+			return nil, err
+		}
+		// Only the recording itself has the ISRC, the first result is trusted if its length fits.
+		if len(tracks) > 0 && similarLength(info.Length, tracks[0].Info.Length) {
+			return tracks[0], nil
+		}
+	}
+	query := info.Title
+	if info.Author != "" {
+		query = info.Author + " " + info.Title
+	}
+	tracks, err := n.mirrorSearch(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tracks {
+		if want.Matches(t) {
+			return t, nil
+		}
+	}
+	return nil, ErrNoMirror
+}
+
+// Mirrors every wanted track like `Node.Mirror`, running up to concurrency searches at a time.
+// Matches keep the order of wants and take over their UserData and Requester, wants without a
+// match are left out. Fails with ctx's error once it's done, and when nothing matched with the
+// first error other than ErrNoMirror, so an unreachable node isn't reported as ErrNoMirror.
+func (n *Node) MirrorMany(ctx context.Context, wants []*Track, concurrency int) ([]*Track, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	mirrored := make([]*Track, len(wants))
+	errs := make([]error, len(wants))
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for i, want := range wants {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, want *Track) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			t, err := n.Mirror(ctx, want.Info)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			t.UserData = want.UserData
+			t.Requester = want.Requester
+			mirrored[i] = t
+		}(i, want)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tracks := make([]*Track, 0, len(mirrored))
+	for _, t := range mirrored {
+		if t != nil {
+			tracks = append(tracks, t)
+		}
+	}
+	if len(tracks) > 0 {
+		return tracks, nil
+	}
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, ErrNoMirror) {
+			return nil, err
+		}
+	}
+	return nil, ErrNoMirror
+}
+
+func (n *Node) mirrorSearch(ctx context.Context, query string) ([]*Track, error) {
+	sr, err := n.searchContext(ctx, YouTubeMusic, query)
+	if err != nil {
+		return nil, err
+	}
+	if sr.Status == LoadFailedSearchStatus {
+		return nil, sr.Exception
+	}
+	return sr.Tracks, nil
+}
+
+// Whether two lengths are within `matchLengthTolerance`, true if either is unknown.
+func similarLength(a, b time.Duration) bool {
+	if a == 0 || b == 0 {
+		return true
+	}
+	diff := a - b
+	return diff >= -matchLengthTolerance && diff <= matchLengthTolerance
+}
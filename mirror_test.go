@@ -0,0 +1,64 @@
+package lavago
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Node whose REST requests go to handler.
+func restNode(t *testing.T, handler http.HandlerFunc) *Node {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	cfg := NewConfig()
+	cfg.Endpoints = []string{strings.TrimPrefix(srv.URL, "http://")}
+	n, err := NewNode(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMirrorMany(t *testing.T) {
+	n := restNode(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Query().Get("identifier"), "Never Gonna Give You Up") {
+			w.Write(fixture(t, "v3", "loadSearch"))
+			return
+		}
+		w.Write(fixture(t, "v3", "loadEmpty"))
+	})
+	wants := []*Track{
+		{Info: TrackInfo{Title: "Unknown Song", Author: "Nobody"}, UserData: 1},
+		{Info: TrackInfo{Title: "Never Gonna Give You Up", Author: "Rick Astley", Length: 212 * time.Second}, UserData: 2, Requester: "3"},
+	}
+	tracks, err := n.MirrorMany(context.Background(), wants, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 1 || tracks[0].UserData != 2 || tracks[0].Requester != "3" {
+		t.Fatalf("got %+v, want the second track with its UserData and Requester", tracks)
+	}
+	if _, err := n.MirrorMany(context.Background(), wants[:1], 2); err != ErrNoMirror {
+		t.Errorf("err = %v, want %v", err, ErrNoMirror)
+	}
+}
+
+func TestMirrorManyUnreachable(t *testing.T) {
+	n := restNode(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	})
+	wants := []*Track{{Info: TrackInfo{Title: "Never Gonna Give You Up"}}}
+	if _, err := n.MirrorMany(context.Background(), wants, 1); err == nil || errors.Is(err, ErrNoMirror) {
+		t.Errorf("err = %v, want the node's error", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := n.MirrorMany(ctx, wants, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
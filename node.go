@@ -1,43 +1,78 @@
 package lavago
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"sync"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
+// Information about a player created by `Node.Join`.
+type PlayerCreatedEvent struct {
+	Player *Player
+}
+
+// Information about a destroyed player.
+type PlayerDestroyedEvent struct {
+	Player *Player
+}
+
 // Contains information about track position.
 type PlayerUpdatedEvent struct {
 	// Player for which this event fired.
 	Player *Player `json:"-,omitempty"`
 	// Track sent by Lavalink.
-	Track *Track `json:"track,omitempty"`
-	State struct {
-		// Track's current position
-		Position  int   `json:"position,omitempty"`
-		Time      int64 `json:"time,omitempty"`
-		Connected bool  `json:"connected,omitempty"`
-	} `json:"state,omitempty"`
-}
-
-// Information about Lavalink statistics.
-type StatsReceivedEvent struct {
-	// Machine's CPU info.
-	CPU string `json:"cpu,omitempty"`
-	// Audio frames.
-	Frames string `json:"frames,omitempty"`
-	// General memory information about Lavalink.
-	Memory string `json:"memory,omitempty"`
-	// Connected players.
-	Players int `json:"players,omitempty"`
-	// Players that are currently playing.
-	PlayingPlayers int `json:"playing_players,omitempty"`
-	// Lavalink uptime.
-	Uptime time.Time `json:"uptime,omitempty"`
+	Track *Track            `json:"track,omitempty"`
+	State PlayerUpdateState `json:"state,omitempty"`
+}
+
+// Player state sent with a playerUpdate.
+type PlayerUpdateState struct {
+	// Track's current position
+	Position time.Duration `json:"position,omitempty"`
+	// Unix timestamp in milliseconds.
+	Time int64 `json:"time,omitempty"`
+	// Whether Lavalink is connected to Discord's voice server.
+	Connected bool `json:"connected,omitempty"`
+	// Round trip to Discord's voice server, negative while not connected. Only sent by Lavalink v3.7 and later.
+	Ping time.Duration `json:"ping,omitempty"`
+}
+
+func (s *PlayerUpdateState) UnmarshalJSON(data []byte) error {
+	type state PlayerUpdateState
+	aux := struct {
+		*state
+		Position millis `json:"position,omitempty"`
+		Ping     millis `json:"ping,omitempty"`
+	}{state: (*state)(s)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	s.Position = time.Duration(aux.Position)
+	s.Ping = time.Duration(aux.Ping)
+	return nil
+}
+
+// Information about a node's session once it's ready.
+type ReadyEvent struct {
+	// Whether a previous session was resumed.
+	Resumed bool
+	// Session ID, only sent by Lavalink v4.
+	SessionID string
+	// Lavalink API version negotiated during the handshake.
+	APIVersion int
+	// Headers Lavalink responded to the websocket upgrade with.
+	Headers http.Header
 }
 
 // Information about the track that started.
@@ -66,8 +101,16 @@ const (
 	// of time passed since the last call to AudioPlayer#provide() has reached the threshold specified in player manager
 	// configuration. This may also indicate either a leaked audio player which was discarded, but not stopped.
 	CleanupReason TrackEndReason = 'C'
+	// The node's connection was lost and its session couldn't be resumed, so Lavalink dropped the player.
+	// Never sent by Lavalink, the event is fired by Lavago.
+	ConnectionLostReason TrackEndReason = 'X'
 )
 
+// Whether a queue should start its next track after a track ended for this reason.
+func (r TrackEndReason) MayStartNext() bool {
+	return r == FinishedReason || r == LoadFailedReason
+}
+
 // Information about track that ended.
 type TrackEndedEvent struct {
 	// Player for which this event fired.
@@ -84,8 +127,10 @@ type TrackExceptionEvent struct {
 	Player *Player `json:"-"`
 	// Track sent by Lavalink.
 	Track *Track `json:"track,omitempty"`
-	// Reason for why track threw an exception.
+	// Reason for why track threw an exception, same as Exception.Message.
 	ErrorMessage string `json:"error_message,omitempty"`
+	// Exception with its severity, Severity is empty if the server didn't send one.
+	Exception TrackException `json:"exception"`
 }
 
 // Information about track that got stuck.
@@ -110,73 +155,394 @@ type WebSocketClosedEvent struct {
 	ByRemote bool `json:"by_remote,omitempty"`
 }
 
-type voiceState struct {
-	GuildID   string
-	SessionID string
+// Describes the connection status of a `Node`.
+type NodeState byte
+
+const (
+	// Not connected to Lavalink.
+	NodeStateDisconnected NodeState = iota
+	// Dialing Lavalink, possibly retrying.
+	NodeStateConnecting
+	// Connected and usable.
+	NodeStateConnected
+	// Connected and resuming a previous session.
+	NodeStateResuming
+	// Closing the connection.
+	NodeStateDraining
+)
+
+func (s NodeState) String() string {
+	switch s {
+	case NodeStateDisconnected:
+		return "Disconnected"
+	case NodeStateConnecting:
+		return "Connecting"
+	case NodeStateConnected:
+		return "Connected"
+	case NodeStateResuming:
+		return "Resuming"
+	case NodeStateDraining:
+		return "Draining"
+	}
+	return fmt.Sprintf("NodeState(%d)", byte(s))
 }
 
 type Node struct {
-	cfg         *Config
-	socket      *Socket
-	connected   bool
-	players     *sync.Map // map[string(GuildID)]*Player
-	voiceStates *sync.Map // map[string(GuildID)]voiceState
-
-	ConnectVoice    func(guildID, channelID string, deaf bool) error
-	PlayerUpdated   func(PlayerUpdatedEvent)
-	StatsReceived   func(StatsReceivedEvent)
+	// Shared with socket, swapped by ApplyConfig.
+	cfg    *configRef
+	socket *Socket
+	state  NodeState
+	// When the current connection was established.
+	connectedAt time.Time
+	connects    int
+	// Handshake values of the last Connect, reused when reconnecting.
+	userID     string
+	shardCount string
+	// Whether the current connection replaced a lost one and its session may have been resumed.
+	reconnecting bool
+	// Player messages sent while reconnecting.
+	ops        opQueue
+	apiVersion int
+	sessionID  string
+	// What the server supports, nil until checked.
+	caps       *Capabilities
+	httpClient *http.Client
+	degraded   bool
+	// Last periodic health check.
+	health Health
+	// Stops the running health checks, see `Config.HealthCheckInterval`.
+	stopHealth context.CancelFunc
+	// Paces REST requests, see `Config.RequestRate`.
+	limiter rateLimiter
+	// Last received stats, oldest first.
+	stats []StatsReceivedEvent
+	// Lifecycle of the current connection, cancelled by Close.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// Set by the node's `Pool` to move players elsewhere once they're dropped.
+	dropped    func(lost []lostPlayer, failover bool)
+	mu         sync.RWMutex
+	players    *sync.Map // map[string(GuildID)]*Player
+	voiceConns *sync.Map // map[string(GuildID)]*voiceConnection
+
+	// Suggests related tracks for autoplay and `Player.Recommend`. Defaults to a `MixRecommender`.
+	Recommender Recommender
+
+	// Fired once the node is usable, after the handshake on Lavalink v3 or the ready op on v4.
+	Ready        func(ReadyEvent)
+	ConnectVoice func(guildID, channelID string, deaf bool) error
+	// Asks Discord to leave the guild's voice channel. Called by Leave and for every player on Close.
+	DisconnectVoice func(guildID string) error
+	// Voice channel a user is in, empty if they aren't in voice. Used by `GuildHandle.PlayQuery`
+	// and set by `UseGateway` for bridges implementing `VoiceStateLookup`.
+	UserVoiceChannel func(guildID, userID string) (string, error)
+	PlayerUpdated    func(PlayerUpdatedEvent)
+	// Fired on every player state transition, useful for keeping UIs in sync.
+	PlayerStateChanged func(PlayerStateChangedEvent)
+	StatsReceived      func(StatsReceivedEvent)
+	// Fired when frame stats show sustained packet loss, see `Config.Degradation`.
+	NodeDegraded func(NodeDegradedEvent)
+	// Fired when a periodic health check fails, see `Config.HealthCheckInterval`.
+	NodeUnhealthy   func(NodeUnhealthyEvent)
 	TrackStarted    func(TrackStartedEvent)
 	TrackEnded      func(TrackEndedEvent)
 	TrackException  func(TrackExceptionEvent)
 	TrackStuck      func(TrackStuckEvent)
 	WebSocketClosed func(WebSocketClosedEvent)
+	// Fired when the bot was moved to another voice channel.
+	PlayerMoved func(PlayerMovedEvent)
+	// Fired when the bot was disconnected from its voice channel by someone else.
+	PlayerKicked func(PlayerKickedEvent)
+	// Fired once Lavalink was sent a complete voice handshake for a guild.
+	VoiceConnected func(VoiceConnectedEvent)
+	// Fired when Join created a new player.
+	PlayerCreated func(PlayerCreatedEvent)
+	// Fired when a player was destroyed, whether through Leave, a kick, voice recovery or Close.
+	PlayerDestroyed func(PlayerDestroyedEvent)
+	// Fired periodically while players are playing, see `Player.StartPositionTicks`.
+	PositionTick func(PositionTickEvent)
+	// Fired when a playerUpdate reports Lavalink lost its connection to Discord's voice server.
+	VoiceDisconnected func(VoiceDisconnectedEvent)
+	// Fired when autoplay picks a related track because the queue ran out.
+	AutoplayTrackSelected func(AutoplayTrackSelectedEvent)
+	// Fired for background errors and undecodable messages, which are printed when it's nil.
+	ErrorReceived func(ErrorEvent)
+	// Fired when the node's websocket closed, whether by Lavalink, a lost connection or Close.
+	NodeSocketClosed func(NodeSocketClosedEvent)
+	// Fired before every delayed retry while connecting or reconnecting, see `Config.Backoff`.
+	NodeConnecting func(NodeConnectingEvent)
+	// Fired for every failed attempt to connect, including ones the node retries.
+	NodeConnectFailed func(NodeConnectFailedEvent)
+	// Fired when event handlers can't keep up with incoming messages, see `Config.SlowConsumerThreshold`.
+	SlowConsumer func(SlowConsumerEvent)
 }
 
 func NewNode(cfg *Config) (*Node, error) {
+	socket := NewSocket(cfg)
 	n := &Node{
-		cfg:         cfg,
-		socket:      NewSocket(cfg),
-		players:     &sync.Map{},
-		voiceStates: &sync.Map{},
+		cfg:        socket.cfg,
+		socket:     socket,
+		httpClient: cfg.httpClient(),
+		players:    &sync.Map{},
+		voiceConns: &sync.Map{},
 	}
+	n.Recommender = NewMixRecommender(n)
 	n.socket.DataReceived = n.socketDataReceived
 	n.socket.ErrorReceived = n.socketOnError
 	n.socket.OnOpen = n.socketOnOpen
+	n.socket.OnClose = n.socketOnClose
+	n.socket.OnSlowConsumer = n.socketSlowConsumer
+	n.socket.OnConnectFailed = n.socketOnConnectFailed
+	n.socket.OnRetry = n.socketOnRetry
 	return n, nil
 }
 
+// Returned by Connect unless the node is disconnected.
+var ErrAlreadyConnected = errors.New("node is already connected or connecting")
+
 func (n *Node) Connect(userID, shardCount string) error {
+	n.mu.Lock()
+	if n.state != NodeStateDisconnected {
+		n.mu.Unlock()
+		return ErrAlreadyConnected
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n.ctx, n.cancel = ctx, cancel
+	n.state = NodeStateConnecting
+	n.userID, n.shardCount = userID, shardCount
+	n.reconnecting = false
+	n.mu.Unlock()
+	err := n.dial(ctx)
+	if err != nil {
+		cancel()
+		n.setState(NodeStateDisconnected)
+		return err
+	}
+	n.startHealthChecks(ctx)
+	n.announce()
+	return nil
+}
+
+// Opens the websocket with the handshake values of the last Connect. Goroutines tied to ctx,
+// like health checks, keep running across reconnects.
+func (n *Node) dial(ctx context.Context) error {
+	auth, err := n.config().authorization(ctx)
+	if err != nil {
+		return err
+	}
+	n.mu.RLock()
+	userID, shardCount, sessionID := n.userID, n.shardCount, n.sessionID
+	n.mu.RUnlock()
 	headers := http.Header{}
 	headers.Add("User-Id", userID)
 	headers.Add("Num-Shards", shardCount)
-	headers.Add("Authorization", n.cfg.Authorization)
-	headers.Add("Client-Name", "Lavago")
-	if n.cfg.EnableResume {
-		headers.Add("Resume-Key", n.cfg.ResumeKey)
+	headers.Add("Authorization", auth)
+	headers.Add("Client-Name", n.config().clientName())
+	if n.config().EnableResume {
+		headers.Add("Resume-Key", n.config().ResumeKey)
+		// Lavalink v4 resumes the session named here instead.
+		if sessionID != "" {
+			headers.Add("Session-Id", sessionID)
+		}
+	}
+	if n.config().UserAgent != "" {
+		headers.Add("User-Agent", n.config().UserAgent)
 	}
-	if n.cfg.UserAgent != "" {
-		headers.Add("User-Agent", n.cfg.UserAgent)
+	req, err := http.NewRequest("GET", n.config().socketEndpoint(n.socket.address()), nil)
+	if err != nil {
+		return err
 	}
-	err := n.socket.Connect(headers)
+	req.Header = headers
+	n.prepareRequest(req)
+	err = n.socket.connect(ctx, req.Header)
 	if err != nil {
 		return err
 	}
-	n.connected = true
+	handshake := n.socket.handshakeHeader()
+	resumed := handshake.Get("Session-Resumed") == "true"
+	n.mu.Lock()
+	n.connectedAt = time.Now()
+	n.connects++
+	n.apiVersion = n.socket.apiVersion()
+	n.caps = nil
+	n.health = Health{}
+	if resumed {
+		n.state = NodeStateResuming
+	} else {
+		n.state = NodeStateConnected
+	}
+	n.mu.Unlock()
 	return nil
 }
 
+// Fires Ready after dialing. Lavalink v4 announces the session with a ready op instead.
+func (n *Node) announce() {
+	n.mu.RLock()
+	apiVersion := n.apiVersion
+	n.mu.RUnlock()
+	if apiVersion < 4 {
+		n.ready(n.socket.handshakeHeader().Get("Session-Resumed") == "true", "")
+	}
+}
+
+func (n *Node) ready(resumed bool, sessionID string) {
+	n.mu.Lock()
+	lost := n.reconnecting && !resumed
+	n.reconnecting = false
+	n.mu.Unlock()
+	n.logf(LogInfo, "ready, session %q resumed: %v", sessionID, resumed)
+	n.probeCapabilities()
+	if lost {
+		// Players are gone along with the session, so are the tracks the messages refer to.
+		n.dropOps()
+		n.endLostTracks()
+	} else {
+		n.players.Range(func(_, v interface{}) bool {
+			v.(*Player).stopBuffering()
+			return true
+		})
+		n.replayOps()
+	}
+	if n.Ready == nil {
+		return
+	}
+	n.mu.RLock()
+	e := ReadyEvent{
+		Resumed:    resumed,
+		SessionID:  sessionID,
+		APIVersion: n.apiVersion,
+		Headers:    n.socket.handshakeHeader(),
+	}
+	n.mu.RUnlock()
+	n.Ready(e)
+}
+
 func (n *Node) Close() error {
-	if !n.connected {
+	state := n.State()
+	if state != NodeStateConnected && state != NodeStateResuming && state != NodeStateConnecting {
 		return errors.New("can't close non-connected node")
 	}
-	n.connected = false
-	n.players = nil
-	n.voiceStates = nil
-	return n.socket.Close()
+	n.setState(NodeStateDraining)
+	n.mu.RLock()
+	cancel := n.cancel
+	n.mu.RUnlock()
+	cancel()
+	err := n.socket.Close()
+	if state == NodeStateConnecting {
+		// The connection was lost already, a reconnect attempt is being cancelled.
+		err = nil
+	} else {
+		n.socketClosed(NodeSocketClosedEvent{Node: n, Code: websocket.CloseNormalClosure})
+	}
+	lost := n.snapshotPlayers()
+	n.dropPlayers()
+	n.setState(NodeStateDisconnected)
+	n.playersDropped(lost, false)
+	return err
+}
+
+// Forgets every player and leaves their voice channels, once Lavalink dropped them or will.
+func (n *Node) dropPlayers() {
+	n.players.Range(func(k, v interface{}) bool {
+		n.players.Delete(k)
+		if err := n.disconnectVoice(k.(string)); err != nil {
+			n.socketOnError(err)
+		}
+		n.playerDestroyed(v.(*Player))
+		return true
+	})
+	clearMap(n.voiceConns)
+}
+
+// Context cancelled when the node is closed, for goroutines tied to the current connection.
+func (n *Node) lifecycle() (context.Context, context.CancelFunc) {
+	n.mu.RLock()
+	parent := n.ctx
+	n.mu.RUnlock()
+	if parent == nil {
+		parent = closedContext()
+	}
+	return context.WithCancel(parent)
+}
+
+// Blocks until every goroutine started for the node's last connection returned, including
+// event handlers, once the node was closed. Must not be called from an event handler.
+func (n *Node) Wait() {
+	n.socket.Wait()
+}
+
+func clearMap(m *sync.Map) {
+	m.Range(func(k, _ interface{}) bool {
+		m.Delete(k)
+		return true
+	})
+}
+
+// Identifies the node within a `Pool`, see `Config.Name`.
+func (n *Node) ID() string {
+	return n.config().name()
+}
+
+func (n *Node) setState(state NodeState) {
+	n.mu.Lock()
+	n.state = state
+	n.mu.Unlock()
+}
+
+// The node's connection status.
+func (n *Node) State() NodeState {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.state
+}
+
+// Whether the node is connected and usable.
+func (n *Node) IsConnected() bool {
+	state := n.State()
+	return state == NodeStateConnected || state == NodeStateResuming
+}
+
+// How long the current connection has been open, zero if disconnected.
+func (n *Node) Uptime() time.Duration {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if n.state != NodeStateConnected && n.state != NodeStateResuming {
+		return 0
+	}
+	return time.Since(n.connectedAt)
+}
+
+// How many times the node connected again after its first connection.
+func (n *Node) Reconnects() int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if n.connects == 0 {
+		return 0
+	}
+	return n.connects - 1
+}
+
+// Lavalink API version reported during the handshake, zero if not connected yet.
+func (n *Node) APIVersion() int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.apiVersion
+}
+
+// Client-Name header the node identifies itself with.
+func (n *Node) ClientName() string {
+	return n.config().clientName()
+}
+
+// Session ID assigned by Lavalink, only sent by Lavalink v4.
+func (n *Node) SessionID() string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.sessionID
 }
 
 func (n *Node) Join(guildID, voiceChannelID string) (*Player, error) {
-	if !n.connected {
+	if !n.IsConnected() {
 		return nil, errors.New("can't join on non-connected node")
 	}
 	if voiceChannelID == "" {
@@ -186,33 +552,119 @@ func (n *Node) Join(guildID, voiceChannelID string) (*Player, error) {
 	if exists {
 		return playerI.(*Player), nil
 	}
+	if n.Overloaded() {
+		return nil, ErrNodeOverloaded
+	}
 
 	if n.ConnectVoice != nil {
-		err := n.ConnectVoice(guildID, voiceChannelID, n.cfg.SelfDeaf)
+		err := n.ConnectVoice(guildID, voiceChannelID, n.config().SelfDeaf)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	cfg := n.config()
 	p := NewPlayer(n.socket, guildID)
+	p.ChannelID = voiceChannelID
+	p.node = n
+	p.stateChanged = n.playerStateChanged
+	p.maxHistory = cfg.HistorySize
+	p.Queue.Policy = cfg.QueuePolicy
+	p.autoplay = cfg.Scheduler.Autoplay
+	p.resetFilters = cfg.ResetFiltersOnTrackChange
+	p.stayConnected = cfg.StayConnected
 	n.players.Store(guildID, p)
+	if cfg.PositionTickInterval > 0 {
+		p.StartPositionTicks(cfg.PositionTickInterval)
+	}
+	if cfg.Scheduler.AutoAdvance && cfg.Scheduler.PreloadWindow > 0 {
+		n.startPreloader(p)
+	}
+	if n.PlayerCreated != nil {
+		n.PlayerCreated(PlayerCreatedEvent{Player: p})
+	}
+	return p, nil
+}
+
+// Returned by `Node.JoinOrGet` when the guild's player is in another voice channel.
+type ChannelMismatchError struct {
+	// The guild's existing player.
+	Player *Player
+	// Channel the player is connected to.
+	ChannelID string
+	// Channel that was requested.
+	Requested string
+}
+
+func (e *ChannelMismatchError) Error() string {
+	return fmt.Sprintf("player is connected to channel %v, not %v", e.ChannelID, e.Requested)
+}
+
+// Like Join, but makes sure an existing player is in voiceChannelID. A player in another channel
+// is moved there if `Config.MoveOnJoin` is set, otherwise a *ChannelMismatchError is returned.
+func (n *Node) JoinOrGet(guildID, voiceChannelID string) (*Player, error) {
+	p := n.GetPlayer(guildID)
+	if p == nil {
+		return n.Join(guildID, voiceChannelID)
+	}
+	p.RLock()
+	current := p.ChannelID
+	p.RUnlock()
+	if current == voiceChannelID {
+		return p, nil
+	}
+	if !n.config().MoveOnJoin {
+		return p, &ChannelMismatchError{Player: p, ChannelID: current, Requested: voiceChannelID}
+	}
+	if n.ConnectVoice != nil {
+		err := n.ConnectVoice(guildID, voiceChannelID, n.config().SelfDeaf)
+		if err != nil {
+			return p, err
+		}
+	}
+	// PlayerMoved fires once Discord confirms the move.
 	return p, nil
 }
 
 func (n *Node) Leave(guildID string) error {
-	if !n.connected {
+	if !n.IsConnected() {
 		return errors.New("can't leave on non-connected node")
 	}
 	playerI, exists := n.players.Load(guildID)
 	if !exists {
 		return nil
 	}
-	p := playerI.(*Player)
+	err := n.destroyPlayer(playerI.(*Player))
+	if dErr := n.disconnectVoice(guildID); err == nil {
+		err = dErr
+	}
+	return err
+}
+
+// Asks Discord to leave the guild's voice channel through DisconnectVoice, if set.
+func (n *Node) disconnectVoice(guildID string) error {
+	if n.DisconnectVoice == nil {
+		return nil
+	}
+	return n.DisconnectVoice(guildID)
+}
+
+// Destroys the player on Lavalink and forgets it along with its voice connection.
+func (n *Node) destroyPlayer(p *Player) error {
 	err := p.Close()
-	n.players.Delete(guildID)
+	n.players.Delete(p.GuildID)
+	n.voiceConns.Delete(p.GuildID)
+	n.playerDestroyed(p)
 	return err
 }
 
+func (n *Node) playerDestroyed(p *Player) {
+	if n.PlayerDestroyed == nil {
+		return
+	}
+	n.PlayerDestroyed(PlayerDestroyedEvent{Player: p})
+}
+
 func (n *Node) HasPlayer(guildID string) bool {
 	_, exists := n.players.Load(guildID)
 	return exists
@@ -227,86 +679,332 @@ func (n *Node) GetPlayer(guildID string) *Player {
 }
 
 func (n *Node) Search(stype SearchType, query string) (*SearchResult, error) {
+	return n.searchContext(context.Background(), stype, query)
+}
+
+func (n *Node) searchContext(ctx context.Context, stype SearchType, query string) (*SearchResult, error) {
+	urlPath, err := n.searchPath(stype, query)
+	if err != nil {
+		return nil, err
+	}
+	sr := &SearchResult{}
+	err = n.getContext(ctx, urlPath, sr)
+	if err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
+
+func (n *Node) searchPath(stype SearchType, query string) (string, error) {
 	if query == "" {
-		return nil, errors.New("can't search with empty query string")
+		return "", errors.New("can't search with empty query string")
+	}
+	if stype == DefaultSearch {
+		stype = n.config().DefaultSearchSource
 	}
-	urlPath := ""
 	switch stype {
 	case SoundCloud:
-		urlPath = "/loadtracks?identifier=scsearch:" + url.QueryEscape(query)
+		query = "scsearch:" + query
 	case YouTubeMusic:
-		urlPath = "/loadtracks?identifier=ytmsearch:" + url.QueryEscape(query)
+		query = "ytmsearch:" + query
 	case YouTube:
-		urlPath = "/loadtracks?identifier=ytsearch:" + url.QueryEscape(query)
-	case Direct:
-		urlPath = "/loadtracks?identifier=" + url.QueryEscape(query)
-	default:
-		urlPath = "/loadtracks?identifier=" + url.QueryEscape(query)
+		query = "ytsearch:" + query
+	}
+	return n.loadPath(query), nil
+}
+
+// Path loading identifier as is.
+func (n *Node) loadPath(identifier string) string {
+	return n.restPath("/loadtracks?identifier=" + url.QueryEscape(identifier))
+}
+
+// Lavalink v4 serves its REST API below /v4.
+func (n *Node) restPath(urlPath string) string {
+	if n.APIVersion() >= 4 {
+		return "/v4" + urlPath
+	}
+	return urlPath
+}
+
+// Loads query directly if it's a URL and searches `Config.DefaultSearchSource` for it otherwise.
+func (n *Node) Resolve(query string) (*SearchResult, error) {
+	return n.resolveContext(context.Background(), query)
+}
+
+func (n *Node) resolveContext(ctx context.Context, query string) (*SearchResult, error) {
+	stype := DefaultSearch
+	if u, err := url.Parse(query); err == nil && u.Scheme != "" && u.Host != "" {
+		stype = Direct
+	}
+	return n.searchContext(ctx, stype, query)
+}
+
+// Decodes a base64 encoded track using Lavalink's REST API.
+func (n *Node) DecodeTrack(encoded string) (*Track, error) {
+	if encoded == "" {
+		return nil, errors.New("can't decode empty track")
+	}
+	t := &Track{}
+	var err error
+	if n.APIVersion() >= 4 {
+		err = n.get("/v4/decodetrack?encodedTrack="+url.QueryEscape(encoded), t)
+	} else {
+		// Lavalink v3 responds with the track's info only.
+		err = n.get("/decodetrack?track="+url.QueryEscape(encoded), &t.Info)
 	}
-	req, err := http.NewRequest("GET", n.cfg.httpEndpoint()+urlPath, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Authorization", n.cfg.Authorization)
+	t.Track = encoded
+	return t, nil
+}
+
+// Performs an authorized GET request against the node and decodes the JSON response into v.
+func (n *Node) get(urlPath string, v interface{}) error {
+	return n.getContext(context.Background(), urlPath, v)
+}
 
-	res, err := http.DefaultClient.Do(req)
+func (n *Node) getContext(ctx context.Context, urlPath string, v interface{}) error {
+	res, err := n.requestContext(ctx, "GET", urlPath, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer res.Body.Close()
+	return json.NewDecoder(res.Body).Decode(v)
+}
 
-	sr := &SearchResult{}
-	err = json.NewDecoder(res.Body).Decode(sr)
+// Sends an authorized REST request with body encoded as JSON unless it's nil, failing on
+// any status other than 2xx. The caller must close the response's body.
+func (n *Node) request(method, urlPath string, body interface{}) (*http.Response, error) {
+	return n.requestContext(context.Background(), method, urlPath, body)
+}
+
+// Like request, but aborted once ctx is done.
+func (n *Node) requestContext(ctx context.Context, method, urlPath string, body interface{}) (*http.Response, error) {
+	if err := n.limiter.wait(ctx, n.config().RequestRate, n.config().RequestBurst); err != nil {
+		return nil, &RequestError{Method: method, Path: urlPath, Err: err}
+	}
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, n.config().httpEndpoint(n.socket.address())+urlPath, r)
 	if err != nil {
 		return nil, err
 	}
-	return sr, nil
-}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	auth, err := n.config().authorization(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", auth)
+	if n.config().UserAgent != "" {
+		req.Header.Set("User-Agent", n.config().UserAgent)
+	}
+	requestID := ""
+	if n.config().RequestIDHeader != "" {
+		requestID = newRequestID()
+		req.Header.Set(n.config().RequestIDHeader, requestID)
+	}
+	n.prepareRequest(req)
 
-func (n *Node) socketOnOpen() {
-	n.connected = true
-	if n.cfg.EnableResume {
-		data, err := json.Marshal(resumePayload{
-			Op:      "configureResuming",
-			Key:     n.cfg.ResumeKey,
-			Timeout: int(n.cfg.ResumeTimeout.Seconds()),
-		})
-		if err != nil {
-			panic("Could not marshal resume")
+	res, err := n.httpClient.Do(req)
+	if err != nil {
+		return nil, &RequestError{Method: method, Path: urlPath, RequestID: requestID, Err: err}
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		res.Body.Close()
+		return nil, &RequestError{
+			Method:    method,
+			Path:      urlPath,
+			RequestID: requestID,
+			Err:       &statusError{Status: res.Status, StatusCode: res.StatusCode},
 		}
-		err = n.socket.Send(data)
-		if err != nil {
-			panic("Could not set resume")
+	}
+	return res, nil
+}
+
+// Failed REST request.
+type RequestError struct {
+	Method string
+	// Path and query of the request, i.e. "/loadtracks?identifier=...".
+	Path string
+	// ID sent in `Config.RequestIDHeader`, empty if disabled. Lets the error be found in Lavalink's logs.
+	RequestID string
+	Err       error
+}
+
+func (e *RequestError) Error() string {
+	if e.RequestID == "" {
+		return fmt.Sprintf("%v %v: %v", e.Method, e.Path, e.Err)
+	}
+	return fmt.Sprintf("%v %v (request %v): %v", e.Method, e.Path, e.RequestID, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// Random ID for a REST request.
+func newRequestID() string {
+	b := make([]byte, 16)
+	// crypto/rand never fails on supported platforms.
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Non-OK REST response.
+type statusError struct {
+	Status     string
+	StatusCode int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("lavalink responded with %v", e.Status)
+}
+
+// Applies the configured extra headers and hook to an outgoing request.
+func (n *Node) prepareRequest(req *http.Request) {
+	for k, vs := range n.config().ExtraHeaders {
+		for _, v := range vs {
+			req.Header.Add(k, v)
 		}
 	}
+	if n.config().BeforeConnect != nil {
+		n.config().BeforeConnect(req)
+	}
+}
+
+// Finds the track an event refers to, falling back to decoding it when the player no longer knows about it.
+// Decoding happens locally when possible and through the REST API otherwise.
+func (n *Node) eventTrack(p *Player, encoded string) *Track {
+	if t := p.knownTrack(encoded); t != nil {
+		return t
+	}
+	if t, err := DecodeTrackString(encoded); err == nil {
+		return t
+	}
+	t, err := n.DecodeTrack(encoded)
+	if err != nil {
+		n.socketOnError(err)
+		return nil
+	}
+	return t
+}
+
+// Track a start event refers to, the exact one passed to Play even if another Play was
+// issued before the event arrived.
+func (n *Node) startedTrack(p *Player, encoded string) *Track {
+	if t := p.startedTrack(encoded); t != nil {
+		return t
+	}
+	return n.eventTrack(p, encoded)
+}
+
+func (n *Node) playerStateChanged(e PlayerStateChangedEvent) {
+	if n.PlayerStateChanged == nil {
+		return
+	}
+	n.PlayerStateChanged(e)
+}
+
+func (n *Node) socketOnOpen() {
+	// Lavalink v4 configures resuming through the session endpoint once ready.
+	if !n.config().EnableResume || n.socket.apiVersion() >= 4 {
+		return
+	}
+	err := n.sendConfigureResuming(n.config().ResumeKey, n.config().ResumeTimeout)
+	if err != nil && !n.socket.closed() {
+		n.socketOnError(err)
+	}
 }
 
 func (n *Node) socketOnError(err error) {
-	// TODO: make better
-	fmt.Println("ERR: " + err.Error())
+	n.logf(LogError, "%v", err)
+	if n.ErrorReceived != nil {
+		n.ErrorReceived(ErrorEvent{Node: n, Err: err})
+		return
+	}
+	if n.config().Logger == nil {
+		fmt.Println("ERR: " + err.Error())
+	}
+}
+
+func (n *Node) playerUpdated(guildID string, state PlayerUpdateState) {
+	p := n.GetPlayer(guildID)
+	if p == nil {
+		return
+	}
+	if p.applyUpdate(state) && n.VoiceDisconnected != nil {
+		n.VoiceDisconnected(VoiceDisconnectedEvent{Player: p})
+	}
+	if n.PlayerUpdated == nil {
+		return
+	}
+	n.PlayerUpdated(PlayerUpdatedEvent{Player: p, State: state})
 }
 
 func (n *Node) socketDataReceived(data []byte) {
+	n.logf(LogDebug, "received %s", data)
 	if len(data) == 0 {
-		// TODO: Handle
-		// n.logger.LogError(...)
-		panic("*Node.DataReceived: len(data) = 0")
+		n.decodeFailed("", data, errors.New("empty message"))
+		return
 	}
-	bp := &basePayload{}
+	if n.config().StrictDecoding {
+		if op, err := checkStrict(data); err != nil {
+			n.decodeFailed(op, data, err)
+			return
+		}
+	}
+	if guildID, state, ok := parsePlayerUpdate(data); ok {
+		n.playerUpdated(string(guildID), state)
+		return
+	}
+	bp := basePayloadPool.Get().(*basePayload)
+	*bp = basePayload{}
+	defer basePayloadPool.Put(bp)
 	err := json.Unmarshal(data, bp)
 	if err != nil {
-		panic("*Node.DataReceived: json.Unmarshal => " + err.Error())
+		n.decodeFailed("", data, err)
+		return
 	}
 	switch bp.Op {
-	case "stats":
-		if n.StatsReceived == nil {
-			break
+	case "ready":
+		rp := readyPayload{}
+		err = json.Unmarshal(data, &rp)
+		if err != nil {
+			n.decodeFailed(bp.Op, data, err)
+			return
 		}
+		n.mu.Lock()
+		n.sessionID = rp.SessionID
+		if rp.Resumed {
+			n.state = NodeStateResuming
+		}
+		n.mu.Unlock()
+		n.configureSession()
+		// Handshakes that arrived before the session existed couldn't be sent yet.
+		n.voiceConns.Range(func(_, v interface{}) bool {
+			n.flushVoice(v.(*voiceConnection))
+			return true
+		})
+		n.ready(rp.Resumed, rp.SessionID)
+	case "stats":
 		sr := StatsReceivedEvent{}
 		err = json.Unmarshal(data, &sr)
 		if err != nil {
-			panic("*Node.DataReceived: json.Unmarshal 'stats' => " + err.Error())
+			n.decodeFailed(bp.Op, data, err)
+			return
 		}
+		sr.Time = time.Now()
+		n.recordStats(sr)
+		n.checkDegraded()
 		if n.StatsReceived == nil {
 			break
 		}
@@ -315,26 +1013,17 @@ func (n *Node) socketDataReceived(data []byte) {
 		pu := PlayerUpdatedEvent{}
 		err = json.Unmarshal(data, &pu)
 		if err != nil {
-			panic("*Node.DataReceived: json.Unmarshal 'playerUpdated' => " + err.Error())
-		}
-		p := n.GetPlayer(bp.GuildID)
-		if p == nil {
-			break
-		}
-		if p.Track != nil {
-			p.Track.updatePosition(pu.State.Position)
+			n.decodeFailed(bp.Op, data, err)
+			return
 		}
-		p.LastUpdate = time.Unix(pu.State.Time, 0)
-		if n.PlayerUpdated == nil {
-			break
-		}
-		pu.Player = p
-		n.PlayerUpdated(pu)
+		n.playerUpdated(bp.GuildID, pu.State)
 	case "event":
-		rp := recvDataEventPayload{}
-		err = json.Unmarshal(data, &rp)
+		rp := eventPayloadPool.Get().(*EventPayload)
+		defer eventPayloadPool.Put(rp)
+		err = n.codec().DecodeEvent(data, rp)
 		if err != nil {
-			panic("*Node.DataReceived: json.Unmarshal 'event' => " + err.Error())
+			n.decodeFailed(bp.Op, data, err)
+			return
 		}
 		switch rp.Type {
 		case trackStartEvent:
@@ -342,97 +1031,76 @@ func (n *Node) socketDataReceived(data []byte) {
 			if p == nil {
 				break
 			}
-			p.Lock()
-			p.State = PlayerStatePlaying
-			p.Unlock()
+			p.setState(PlayerStatePlaying)
+			started := n.startedTrack(p, rp.Track)
 			if n.TrackStarted == nil {
 				break
 			}
-			n.TrackStarted(TrackStartedEvent{Player: p, Track: p.Track})
+			n.TrackStarted(TrackStartedEvent{Player: p, Track: started})
 		case trackEndEvent:
+			if rp.Reason == "" {
+				n.decodeFailed(bp.Op, data, errors.New("track end without reason"))
+				return
+			}
 			p := n.GetPlayer(bp.GuildID)
 			if p == nil {
 				break
 			}
-			p.Lock()
-			p.State = PlayerStateStopped
-			p.Unlock()
-			if n.TrackEnded == nil {
-				break
+			reason := TrackEndReason(rp.Reason[0])
+			ended := n.eventTrack(p, rp.Track)
+			current := p.endTrack(rp.Track)
+			if current && reason != ReplacedReason {
+				p.setState(PlayerStateStopped)
+			}
+			if n.TrackEnded != nil {
+				n.TrackEnded(TrackEndedEvent{Player: p, Track: ended, Reason: reason})
+			}
+			if current && reason.MayStartNext() {
+				n.advance(p, ended)
 			}
-			n.TrackEnded(TrackEndedEvent{Player: p, Track: p.Track, Reason: TrackEndReason(rp.Reason[0])})
 		case trackExceptionEvent:
 			p := n.GetPlayer(bp.GuildID)
 			if p == nil {
 				break
 			}
-			p.Lock()
-			p.State = PlayerStateStopped
-			p.Unlock()
+			p.setState(PlayerStateStopped)
+			track := n.eventTrack(p, rp.Track)
+			p.recordException(track, rp.Error)
 			if n.TrackException == nil {
 				break
 			}
-			n.TrackException(TrackExceptionEvent{Player: p, Track: p.Track, ErrorMessage: rp.Error})
+			e := TrackExceptionEvent{Player: p, Track: track, ErrorMessage: rp.Error}
+			if rp.Exception != nil {
+				e.Exception = *rp.Exception
+			}
+			n.TrackException(e)
 		case trackStuckEvent:
 			p := n.GetPlayer(bp.GuildID)
 			if p == nil {
 				break
 			}
-			p.Lock()
-			p.State = PlayerStateStopped
-			p.Unlock()
+			p.setState(PlayerStateStopped)
+			track := n.eventTrack(p, rp.Track)
+			p.recordStuck(track, time.Duration(rp.ThresholdMs))
 			if n.TrackStuck == nil {
 				break
 			}
-			dur, err := time.ParseDuration(fmt.Sprintf("%vms", rp.ThresholdMs))
-			if err != nil {
-				panic("*Node.DataReceived: time.ParseDuration 'event' => " + err.Error())
-			}
-			n.TrackStuck(TrackStuckEvent{Player: p, Track: p.Track, Threshold: dur})
+			n.TrackStuck(TrackStuckEvent{Player: p, Track: track, Threshold: time.Duration(rp.ThresholdMs)})
 		case webSocketClosedEvent:
-			if n.WebSocketClosed == nil {
-				break
+			if p := n.GetPlayer(rp.GuildID); p != nil {
+				p.recordVoiceClose(rp.Code, rp.Reason)
 			}
-			n.WebSocketClosed(WebSocketClosedEvent{
-				GuildID:  rp.GuildID,
-				Reason:   rp.Reason,
-				Code:     rp.Code,
-				ByRemote: rp.ByRemote,
-			})
+			if n.WebSocketClosed != nil {
+				n.WebSocketClosed(WebSocketClosedEvent{
+					GuildID:  rp.GuildID,
+					Reason:   rp.Reason,
+					Code:     rp.Code,
+					ByRemote: rp.ByRemote,
+				})
+			}
+			n.recoverVoice(rp.GuildID, rp.Code)
 		}
 	default:
-		panic("*Node.DataReceived: switch.default")
-	}
-}
-
-func (n *Node) OnVoiceStateUpdate(shardUserID, triggerUserID, guildID, sessionID string) {
-	if shardUserID != triggerUserID {
-		return
-	}
-	n.voiceStates.Store(guildID, voiceState{GuildID: guildID, SessionID: sessionID})
-}
-
-func (n *Node) OnVoiceServerUpdate(guildID, endpoint, token string) {
-	vsI, exists := n.voiceStates.Load(guildID)
-	if !exists {
-		return
-	}
-	vs := vsI.(voiceState)
-	sp := &serverUpdatePayload{
-		Op:        "voiceUpdate",
-		GuildID:   vs.GuildID,
-		SessionID: vs.SessionID,
-		Event: voiceServerPayload{
-			Endpoint: endpoint,
-			Token:    token,
-		},
-	}
-	data, err := json.Marshal(sp)
-	if err != nil {
-		panic("*Node.OnVoiceServerUpdate json.Marshal")
-	}
-	err = n.socket.Send(data)
-	if err != nil {
-		fmt.Println("*Node.OnVoiceServerUpdate ERR socked.Send: " + err.Error())
+		// Ops added by newer servers are ignored, strict decoding already rejected them.
 	}
 }
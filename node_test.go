@@ -0,0 +1,27 @@
+package lavago
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPlayerUpdateStateFixtures(t *testing.T) {
+	want := PlayerUpdateState{Position: time.Minute, Time: 1500467109, Connected: true, Ping: 50 * time.Millisecond}
+	for _, version := range []string{"v3", "v4"} {
+		pu := PlayerUpdatedEvent{}
+		if err := json.Unmarshal(fixture(t, version, "playerUpdate"), &pu); err != nil {
+			t.Fatalf("%s: %v", version, err)
+		}
+		if pu.State != want {
+			t.Errorf("%s: got %+v, want %+v", version, pu.State, want)
+		}
+	}
+	rp := RemotePlayer{}
+	if err := json.Unmarshal(fixture(t, "v4", "player"), &rp); err != nil {
+		t.Fatal(err)
+	}
+	if rp.State != want {
+		t.Errorf("player: got %+v, want %+v", rp.State, want)
+	}
+}
@@ -0,0 +1,77 @@
+package lavago
+
+import (
+	"sync"
+	"time"
+)
+
+// Most player messages a node holds while reconnecting.
+const maxQueuedOps = 1024
+
+// Player message held back while the node reconnects, see `Config.QueueOpsMaxAge`.
+type queuedOp struct {
+	msg Message
+	at  time.Time
+}
+
+type opQueue struct {
+	ops []queuedOp
+	// Whether messages are held back, from losing the connection until the queue was replayed.
+	holding bool
+	sync.Mutex
+}
+
+// Starts holding player messages back, called when the node starts reconnecting.
+func (n *Node) holdOps() {
+	n.ops.Lock()
+	n.ops.holding = true
+	n.ops.Unlock()
+}
+
+// Holds msg back if the node is reconnecting and queueing is enabled. Reports whether it did.
+func (n *Node) queueOp(msg Message) bool {
+	if n.config().QueueOpsMaxAge <= 0 {
+		return false
+	}
+	n.ops.Lock()
+	defer n.ops.Unlock()
+	if !n.ops.holding || len(n.ops.ops) == maxQueuedOps {
+		return false
+	}
+	n.ops.ops = append(n.ops.ops, queuedOp{msg: msg, at: time.Now()})
+	return true
+}
+
+// Drops the queued messages and stops holding new ones back.
+func (n *Node) dropOps() {
+	n.ops.Lock()
+	n.ops.ops = nil
+	n.ops.holding = false
+	n.ops.Unlock()
+}
+
+// Sends the messages queued while reconnecting in order, skipping those older than
+// `Config.QueueOpsMaxAge`. Messages are held back until the queue is empty, so the ones
+// queued meanwhile go out after it and new ones can't overtake it.
+func (n *Node) replayOps() {
+	maxAge := n.config().QueueOpsMaxAge
+	for {
+		n.ops.Lock()
+		ops := n.ops.ops
+		n.ops.ops = nil
+		if len(ops) == 0 {
+			n.ops.holding = false
+			n.ops.Unlock()
+			return
+		}
+		n.ops.Unlock()
+		for _, op := range ops {
+			if time.Since(op.at) > maxAge {
+				continue
+			}
+			if err := n.send(op.msg); err != nil {
+				n.socketOnError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package lavago
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueueOpsUntilReplayed(t *testing.T) {
+	n := fixtureNode(t, V3Codec{})
+	cfg := *n.config()
+	cfg.QueueOpsMaxAge = time.Nanosecond
+	n.ApplyConfig(&cfg)
+	msg, err := V3Codec{}.EncodeStop(fixtureGuildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.queueOp(msg) {
+		t.Fatal("queued while connected")
+	}
+	n.holdOps()
+	if !n.queueOp(msg) {
+		t.Fatal("not queued while reconnecting")
+	}
+	// The socket is back before ready replays the queue.
+	n.setState(NodeStateConnected)
+	if !n.queueOp(msg) {
+		t.Fatal("sent ahead of the queue")
+	}
+	time.Sleep(time.Millisecond)
+	// Expired messages are dropped rather than sent.
+	n.replayOps()
+	if n.queueOp(msg) {
+		t.Error("queued after the replay")
+	}
+	n.holdOps()
+	n.queueOp(msg)
+	n.dropOps()
+	if n.queueOp(msg) || len(n.ops.ops) != 0 {
+		t.Error("queue kept after dropping it")
+	}
+}
@@ -1,6 +1,37 @@
 package lavago
 
-import "time"
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+)
+
+// Duration sent over the wire as whole milliseconds, the unit Lavalink uses everywhere.
+type millis time.Duration
+
+func (m millis) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(m).Milliseconds())
+}
+
+func (m *millis) UnmarshalJSON(data []byte) error {
+	var ms float64
+	if err := json.Unmarshal(data, &ms); err != nil {
+		return err
+	}
+	// Lavalink reports the length of streams as Java's Long.MAX_VALUE, which doesn't fit in
+	// nanoseconds.
+	d := ms * float64(time.Millisecond)
+	switch {
+	case d >= math.MaxInt64:
+		*m = millis(math.MaxInt64)
+	case d <= math.MinInt64:
+		*m = millis(math.MinInt64)
+	default:
+		*m = millis(d)
+	}
+	return nil
+}
 
 const (
 	trackStartEvent      = "TrackStartEvent"
@@ -10,23 +41,31 @@ const (
 	webSocketClosedEvent = "WebSocketClosedEvent"
 )
 
-type recvDataEventPayload struct {
-	Op          string `json:"op,omitempty"`
-	GuildID     string `json:"guildId,omitempty"`
-	Type        string `json:"type,omitempty"`
-	Track       string `json:"track,omitempty"`
-	Reason      string `json:"reason,omitempty"`
-	Error       string `json:"error,omitempty"`
-	ThresholdMs int    `json:"thresholdMs,omitempty"`
-	Code        int    `json:"code,omitempty"`
-	ByRemote    bool   `json:"byRemote"`
-}
+// Payloads decoded for every received message are pooled, callers must reset them after Get.
+var (
+	basePayloadPool = sync.Pool{
+		New: func() interface{} {
+			return new(basePayload)
+		},
+	}
+	eventPayloadPool = sync.Pool{
+		New: func() interface{} {
+			return new(EventPayload)
+		},
+	}
+)
 
 type basePayload struct {
 	Op      string `json:"op,omitempty"`
 	GuildID string `json:"guildId,omitempty"`
 }
 
+type readyPayload struct {
+	Op        string `json:"op,omitempty"`
+	Resumed   bool   `json:"resumed"`
+	SessionID string `json:"sessionId,omitempty"`
+}
+
 type resumePayload struct {
 	Op      string `json:"op,omitempty"`
 	Key     string `json:"key,omitempty"`
@@ -46,14 +85,14 @@ type voiceServerPayload struct {
 }
 
 type playerPlayPayload struct {
-	Op        string        `json:"op,omitempty"`
-	GuildID   string        `json:"guildId,omitempty"`
-	Track     string        `json:"track,omitempty"`
-	NoReplace bool          `json:"noReplace,omitempty"`
-	StartTime time.Duration `json:"startTime,omitempty"`
-	EndTime   time.Duration `json:"endTime,omitempty"`
-	Volume    int           `json:"volume,omitempty"`
-	Pause     bool          `json:"pause"`
+	Op        string `json:"op,omitempty"`
+	GuildID   string `json:"guildId,omitempty"`
+	Track     string `json:"track,omitempty"`
+	NoReplace bool   `json:"noReplace,omitempty"`
+	StartTime millis `json:"startTime,omitempty"`
+	EndTime   millis `json:"endTime,omitempty"`
+	Volume    int    `json:"volume,omitempty"`
+	Pause     bool   `json:"pause"`
 }
 
 type playerStopPayload struct {
@@ -70,7 +109,7 @@ type playerPausePayload struct {
 type playerSeekPayload struct {
 	Op       string `json:"op,omitempty"`
 	GuildID  string `json:"guildId,omitempty"`
-	Position int    `json:"position,omitempty"`
+	Position millis `json:"position"`
 }
 
 type playerVolumePayload struct {
@@ -83,3 +122,15 @@ type playerDestroyPayload struct {
 	Op      string `json:"op,omitempty"`
 	GuildID string `json:"guildId,omitempty"`
 }
+
+type playerEqualizerPayload struct {
+	Op      string          `json:"op,omitempty"`
+	GuildID string          `json:"guildId,omitempty"`
+	Bands   []EqualizerBand `json:"bands"`
+}
+
+type playerFiltersPayload struct {
+	Op      string `json:"op,omitempty"`
+	GuildID string `json:"guildId,omitempty"`
+	Filters
+}
@@ -0,0 +1,48 @@
+package lavago
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMillisUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{`0`, 0},
+		{`212000`, 212 * time.Second},
+		{`1500.5`, 1500*time.Millisecond + 500*time.Microsecond},
+		{`-1`, -time.Millisecond},
+		{`6e4`, time.Minute},
+		// Length of streams.
+		{`9223372036854775807`, math.MaxInt64},
+		{`-9223372036854775808`, math.MinInt64},
+	}
+	for _, tt := range tests {
+		var m millis
+		if err := json.Unmarshal([]byte(tt.in), &m); err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if time.Duration(m) != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.in, time.Duration(m), tt.want)
+		}
+	}
+	var m millis
+	if err := json.Unmarshal([]byte(`"212000"`), &m); err == nil {
+		t.Error("string accepted as milliseconds")
+	}
+}
+
+func TestMillisMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(playerSeekPayload{Op: "seek", GuildID: "1", Position: millis(90*time.Second + 999*time.Microsecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"op":"seek","guildId":"1","position":90000}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
@@ -1,14 +1,10 @@
 package lavago
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
 	"time"
-
-	"github.com/emirpasic/gods/lists"
-	"github.com/emirpasic/gods/lists/arraylist"
 )
 
 // Describes the status of a `Player`
@@ -23,8 +19,70 @@ const (
 	PlayerStateStopped
 	// Playing a track but paused
 	PlayerStatePaused
+	// Waiting for the node to reconnect, see `Config.BufferOnReconnect`. The position is frozen.
+	PlayerStateBuffering
 )
 
+func (s PlayerState) String() string {
+	switch s {
+	case PlayerStateNone:
+		return "None"
+	case PlayerStatePlaying:
+		return "Playing"
+	case PlayerStateStopped:
+		return "Stopped"
+	case PlayerStatePaused:
+		return "Paused"
+	case PlayerStateBuffering:
+		return "Buffering"
+	}
+	return fmt.Sprintf("PlayerState(%d)", byte(s))
+}
+
+// Valid state transitions, keyed by the state being left.
+var playerTransitions = map[PlayerState][]PlayerState{
+	PlayerStateNone:      {PlayerStatePlaying, PlayerStatePaused, PlayerStateStopped},
+	PlayerStatePlaying:   {PlayerStatePlaying, PlayerStatePaused, PlayerStateStopped, PlayerStateNone, PlayerStateBuffering},
+	PlayerStatePaused:    {PlayerStatePlaying, PlayerStatePaused, PlayerStateStopped, PlayerStateNone, PlayerStateBuffering},
+	PlayerStateStopped:   {PlayerStatePlaying, PlayerStatePaused, PlayerStateStopped, PlayerStateNone},
+	PlayerStateBuffering: {PlayerStatePlaying, PlayerStatePaused, PlayerStateStopped, PlayerStateNone},
+}
+
+// Whether the state machine allows moving from s to the given state.
+func (s PlayerState) CanTransition(to PlayerState) bool {
+	for _, st := range playerTransitions[s] {
+		if st == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Returned when the player isn't connected to a voice channel (state is None).
+var ErrPlayerNotConnected = errors.New("player's current state is set to None. Please make sure Player is connected to a voice channel")
+
+// Returned when an operation would move the player into a state it can't reach from its current one.
+type StateTransitionError struct {
+	// State the player was in.
+	From PlayerState
+	// State the operation tried to move to.
+	To PlayerState
+}
+
+func (e *StateTransitionError) Error() string {
+	return fmt.Sprintf("invalid player state transition %v -> %v", e.From, e.To)
+}
+
+// Information about a player changing state.
+type PlayerStateChangedEvent struct {
+	// Player for which this event fired.
+	Player *Player
+	// Previous state.
+	From PlayerState
+	// New state.
+	To PlayerState
+}
+
 // Arguments for Player.Play
 type PlayArgs struct {
 	//  Which track to play
@@ -48,42 +106,323 @@ type Player struct {
 	// Player's current state.
 	State PlayerState
 	// Default queue.
-	Queue lists.List
+	Queue *TrackQueue
 	// Current track that is playing.
 	Track *Track
-	// Voice channel this player is connected to.
+	// Guild this player belongs to.
 	GuildID string
+	// Voice channel this player is connected to.
+	ChannelID string
 	// Player's current volume.
 	Volume int
+	// Decides whether actorID may perform the action through the player's "As" methods, i.e.
+	// PlayAs or SkipAs, keeping permission checks like DJ roles in one place. The returned
+	// error is passed through to the caller. Nil allows everything.
+	Authorize func(action PlayerAction, actorID string) error
 
-	socket *Socket
+	// Track that was replaced by Track but hasn't reported its end yet.
+	replaced *Track
+	// Previously played tracks, oldest first.
+	history       []*Track
+	maxHistory    int
+	autoplay      bool
+	loop          LoopMode
+	stayConnected bool
+	// Voice connection health from the last playerUpdate.
+	voiceConnected bool
+	voicePing      time.Duration
+	// Track to restart once the voice connection is re-established.
+	resumeAfterVoice *PlayArgs
+	// When the current track's position was last known.
+	positionAt time.Time
+	// State to return to once buffering ends.
+	buffered PlayerState
+	// Filters last applied, guarded by the player's lock. filtersMu serializes updates.
+	filters      Filters
+	filtersMu    sync.Mutex
+	resetFilters bool
+	// Active segment loop, see LoopSegment.
+	segment *segmentLoop
+	// Next track already sent to Lavalink by the preloader, see `SchedulerConfig.PreloadWindow`.
+	preloaded *Track
+	// Next track the preloader encoded ahead of time and its encoding.
+	prepared        *Track
+	preparedEncoded string
+	// Tracks sent to Lavalink whose TrackStartEvent hasn't arrived yet, oldest first.
+	pending []*Track
+	// Recent trouble, see Diagnostics.
+	diag playerDiagnostics
+	// Stops the preloader, nil if none is running.
+	stopPreload func()
+	// Stops the position ticker, nil if none is running.
+	stopTicks    func()
+	skipMu       sync.Mutex
+	skipTimer    *time.Timer
+	node         *Node
+	socket       *Socket
+	stateChanged func(PlayerStateChangedEvent)
 	sync.RWMutex
 }
 
+// How many previously played tracks a player remembers by default.
+const defaultHistorySize = 25
+
 // Creates a new player
 func NewPlayer(socket *Socket, guildID string) *Player {
 	return &Player{
-		Queue:   arraylist.New(),
-		GuildID: guildID,
-		socket:  socket,
+		Queue:      NewTrackQueue(),
+		GuildID:    guildID,
+		maxHistory: defaultHistorySize,
+		socket:     socket,
+	}
+}
+
+// Checks that the player may move to the given state. If from is non-empty the current state must be one of them.
+func (p *Player) checkTransition(to PlayerState, from ...PlayerState) error {
+	p.RLock()
+	cur := p.State
+	p.RUnlock()
+	allowed := len(from) == 0
+	for _, st := range from {
+		if st == cur {
+			allowed = true
+			break
+		}
+	}
+	if allowed && cur.CanTransition(to) {
+		return nil
+	}
+	if cur == PlayerStateNone {
+		return ErrPlayerNotConnected
+	}
+	return &StateTransitionError{From: cur, To: to}
+}
+
+func (p *Player) currentState() PlayerState {
+	p.RLock()
+	defer p.RUnlock()
+	return p.State
+}
+
+// Sets the state without validation, used once an operation was sent or when Lavalink reports what actually happened.
+func (p *Player) setState(to PlayerState) {
+	p.Lock()
+	cur := p.State
+	p.State = to
+	p.Unlock()
+	p.emitStateChanged(cur, to)
+}
+
+func (p *Player) emitStateChanged(from, to PlayerState) {
+	if from == to || p.stateChanged == nil {
+		return
+	}
+	p.stateChanged(PlayerStateChangedEvent{Player: p, From: from, To: to})
+}
+
+// Replaces the current track, remembering the old one until Lavalink reports it ended
+// and, if record is set, in the play history. Must hold the lock.
+// Reports whether a different track was replaced.
+func (p *Player) setTrack(track *Track, record bool) bool {
+	replaced := p.Track != nil && p.Track != track
+	if replaced {
+		p.replaced = p.Track
+		if record {
+			p.pushHistory(p.Track)
+		}
+	}
+	p.Track = track
+	if track != nil {
+		track.updatePosition(0)
+	}
+	p.positionAt = time.Now()
+	return replaced
+}
+
+// Records the state reported by a playerUpdate. Reports whether the voice connection dropped since the last one.
+func (p *Player) applyUpdate(state PlayerUpdateState) (disconnected bool) {
+	p.Lock()
+	defer p.Unlock()
+	disconnected = p.voiceConnected && !state.Connected
+	if p.Track != nil {
+		p.Track.updatePosition(state.Position)
+	}
+	p.positionAt = time.Now()
+	if state.Time > 0 {
+		p.LastUpdate = time.UnixMilli(state.Time)
+	}
+	p.voiceConnected = state.Connected
+	p.voicePing = state.Ping
+	return disconnected
+}
+
+// Whether Lavalink reported being connected to Discord's voice server in the last playerUpdate.
+func (p *Player) VoiceConnected() bool {
+	p.RLock()
+	defer p.RUnlock()
+	return p.voiceConnected
+}
+
+// Round trip between Lavalink and Discord's voice server from the last playerUpdate,
+// negative while not connected and zero if Lavalink doesn't report it.
+func (p *Player) VoicePing() time.Duration {
+	p.RLock()
+	defer p.RUnlock()
+	return p.voicePing
+}
+
+// Records the track position reported by Lavalink.
+func (p *Player) updatePosition(pos time.Duration) {
+	p.Lock()
+	if p.Track != nil {
+		p.Track.updatePosition(pos)
+	}
+	p.positionAt = time.Now()
+	p.Unlock()
+}
+
+// Current track position, extrapolated from the last known position while playing. Must hold the lock.
+func (p *Player) position() time.Duration {
+	if p.Track == nil {
+		return 0
+	}
+	pos := p.Track.Info.Position
+	if p.State == PlayerStatePlaying && !p.positionAt.IsZero() {
+		pos += time.Since(p.positionAt)
 	}
+	if length := p.Track.duration(); length > 0 && pos > length {
+		pos = length
+	}
+	return pos
+}
+
+// Marks a playing or paused player as buffering, freezing its position.
+func (p *Player) startBuffering() {
+	p.Lock()
+	cur := p.State
+	if cur != PlayerStatePlaying && cur != PlayerStatePaused {
+		p.Unlock()
+		return
+	}
+	if p.Track != nil {
+		p.Track.updatePosition(p.position())
+	}
+	p.positionAt = time.Now()
+	p.buffered = cur
+	p.State = PlayerStateBuffering
+	p.Unlock()
+	p.emitStateChanged(cur, PlayerStateBuffering)
+}
+
+// Returns a buffering player to the state it had before, continuing from the frozen position.
+func (p *Player) stopBuffering() {
+	p.Lock()
+	if p.State != PlayerStateBuffering {
+		p.Unlock()
+		return
+	}
+	to := p.buffered
+	p.State = to
+	p.positionAt = time.Now()
+	p.Unlock()
+	p.emitStateChanged(PlayerStateBuffering, to)
+}
+
+// Must hold the lock.
+func (p *Player) pushHistory(track *Track) {
+	if p.maxHistory <= 0 {
+		return
+	}
+	p.history = append(p.history, track)
+	if over := len(p.history) - p.maxHistory; over > 0 {
+		p.history = append(p.history[:0], p.history[over:]...)
+	}
+}
+
+// Previously played tracks, ordered from oldest to most recent.
+func (p *Player) History() []*Track {
+	p.RLock()
+	defer p.RUnlock()
+	history := make([]*Track, len(p.history))
+	copy(history, p.history)
+	return history
+}
+
+// Returns the current or replaced track matching the encoded track, if any.
+func (p *Player) knownTrack(encoded string) *Track {
+	p.RLock()
+	defer p.RUnlock()
+	if p.Track != nil && p.Track.Track == encoded {
+		return p.Track
+	}
+	if p.replaced != nil && p.replaced.Track == encoded {
+		return p.replaced
+	}
+	return nil
+}
+
+// Most tracks kept waiting for their start event. Tracks that fail to load never start,
+// so older entries are dropped past this.
+const maxPendingTracks = 8
+
+// Registers a track sent to Lavalink so its start event can be matched to it.
+func (p *Player) addPending(track *Track) {
+	p.Lock()
+	defer p.Unlock()
+	if len(p.pending) == maxPendingTracks {
+		p.pending = p.pending[1:]
+	}
+	p.pending = append(p.pending, track)
+}
+
+// Returns the track sent for a start event and forgets it along with tracks sent before
+// it, whose events arrived already or never will. Nil if it wasn't sent through the player.
+func (p *Player) startedTrack(encoded string) *Track {
+	p.Lock()
+	defer p.Unlock()
+	for i, t := range p.pending {
+		if t.Track == encoded {
+			p.pending = p.pending[i+1:]
+			return t
+		}
+	}
+	return nil
+}
+
+// Forgets the ended track and reports whether it was the current one.
+func (p *Player) endTrack(encoded string) bool {
+	p.Lock()
+	defer p.Unlock()
+	if p.replaced != nil && p.replaced.Track == encoded {
+		p.replaced = nil
+		return false
+	}
+	return p.Track != nil && p.Track.Track == encoded
 }
 
 func (p *Player) Close() error {
+	p.StopPositionTicks()
+	p.stopPreloader()
+	p.ClearSegmentLoop()
 	p.Stop()
-	data, err := json.Marshal(playerDestroyPayload{
-		Op:      "destroy",
-		GuildID: p.GuildID,
-	})
+	msg, err := p.codec().EncodeDestroy(p.GuildID)
 	if err != nil {
 		return err
 	}
 	p.Lock()
-	err = p.socket.Send(data)
+	if p.skipTimer != nil {
+		p.skipTimer.Stop()
+		p.skipTimer = nil
+	}
+	err = p.send(msg)
 	p.Queue.Clear()
 	p.Track = nil
-	p.State = PlayerStateNone
+	p.replaced = nil
+	p.pending = nil
+	p.history = nil
+	p.filters = Filters{}
 	p.Unlock()
+	p.setState(PlayerStateNone)
 	return err
 }
 
@@ -92,26 +431,20 @@ func (p *Player) Play(args PlayArgs) error {
 	if args.Track == nil {
 		return errors.New("can't play nil Track")
 	}
-	p.Lock()
-	if args.ShouldPause {
-		p.State = PlayerStatePaused
-	} else {
-		p.State = PlayerStatePlaying
-	}
-	p.Unlock()
 	if args.Volume < 0 {
 		return errors.New("can't play with volume < 0")
 	}
 	if args.Volume > 1000 {
 		return errors.New("can't play with volume > 1000")
 	}
-	p.Lock()
-	p.Volume = args.Volume
-	p.Track = args.Track
-	p.Unlock()
-	data, err := json.Marshal(playerPlayPayload{
-		Op:        "play",
-		GuildID:   p.GuildID,
+	to := PlayerStatePlaying
+	if args.ShouldPause {
+		to = PlayerStatePaused
+	}
+	if err := p.checkTransition(to); err != nil {
+		return err
+	}
+	msg, err := p.codec().EncodePlay(p.GuildID, PlayOp{
 		Track:     args.Track.Track,
 		NoReplace: args.NoReplace,
 		StartTime: args.StartTime,
@@ -122,145 +455,358 @@ func (p *Player) Play(args PlayArgs) error {
 	if err != nil {
 		return err
 	}
-	return p.socket.Send(data)
+	err = p.send(msg)
+	if err != nil {
+		return err
+	}
+	p.Lock()
+	p.Volume = args.Volume
+	replaced := p.setTrack(args.Track, true)
+	args.Track.updatePosition(args.StartTime)
+	p.Unlock()
+	p.setState(to)
+	if replaced {
+		p.trackReplaced()
+	}
+	return nil
 }
 
 // Plays the specified track.
 func (p *Player) PlayTrack(track *Track) error {
-	if track == nil {
-		return errors.New("can't play nil Track")
+	return p.playTrack(track, true, false)
+}
+
+// Plays the most recent track from the history, putting the current track back at the front of the queue.
+func (p *Player) Previous() (*Track, error) {
+	p.Lock()
+	if len(p.history) == 0 {
+		p.Unlock()
+		return nil, errors.New("can't play previous, history is empty")
+	}
+	prev := p.history[len(p.history)-1]
+	current := p.Track
+	p.Unlock()
+	err := p.playTrack(prev, false, false)
+	if err != nil {
+		return nil, err
 	}
 	p.Lock()
-	p.State = PlayerStatePlaying
-	p.Track = track
+	if n := len(p.history); n > 0 && p.history[n-1] == prev {
+		p.history = p.history[:n-1]
+	}
 	p.Unlock()
-	data, err := json.Marshal(playerPlayPayload{
-		Op:      "play",
-		GuildID: p.GuildID,
-		Track:   track.Track,
-		Volume:  100,
-		Pause:   false,
-	})
+	if current != nil {
+		p.Queue.Insert(0, current)
+	}
+	return prev, nil
+}
+
+// Play op for a track, applying the track's own start, end and volume.
+func (p *Player) trackOp(track *Track, noReplace bool) PlayOp {
+	op := PlayOp{
+		Track:     p.encode(track),
+		NoReplace: noReplace,
+		StartTime: track.StartTime,
+		EndTime:   track.EndTime,
+	}
+	// Without an override the player keeps its volume.
+	if track.Volume > 0 {
+		op.Volume = track.Volume
+	}
+	return op
+}
+
+// With noReplace, Lavalink ignores the play if a track is still playing.
+func (p *Player) playTrack(track *Track, record, noReplace bool) error {
+	if track == nil {
+		return errors.New("can't play nil Track")
+	}
+	if err := p.checkTransition(PlayerStatePlaying); err != nil {
+		return err
+	}
+	msg, err := p.codec().EncodePlay(p.GuildID, p.trackOp(track, noReplace))
 	if err != nil {
 		return err
 	}
-	return p.socket.Send(data)
+	err = p.send(msg)
+	if err != nil {
+		return err
+	}
+	p.addPending(track)
+	p.Lock()
+	replaced := p.setTrack(track, record)
+	track.updatePosition(track.StartTime)
+	if track.Volume > 0 {
+		p.Volume = track.Volume
+	}
+	p.Unlock()
+	p.setState(PlayerStatePlaying)
+	if replaced {
+		p.trackReplaced()
+	}
+	return nil
 }
 
-// Stops the current track if any is playing.
+// Stops the current track if any is playing. Does nothing if the player is already stopped.
 func (p *Player) Stop() error {
-	p.Lock()
-	p.State = PlayerStateStopped
-	p.Unlock()
-	data, err := json.Marshal(playerStopPayload{
-		Op:      "stop",
-		GuildID: p.GuildID,
-	})
+	switch p.currentState() {
+	case PlayerStateStopped, PlayerStateNone:
+		return nil
+	}
+	msg, err := p.codec().EncodeStop(p.GuildID)
 	if err != nil {
 		return err
 	}
-	return p.socket.Send(data)
+	err = p.send(msg)
+	if err != nil {
+		return err
+	}
+	p.setState(PlayerStateStopped)
+	return nil
 }
 
-// Pauses the current track if any is playing.
+// Pauses the current track if any is playing. Does nothing if the player is already paused.
 func (p *Player) Pause() error {
-	if p.State == PlayerStateNone {
-		return errors.New("player's current state is set to None. Please make sure Player is connected to a voice channel")
+	if p.currentState() == PlayerStatePaused {
+		return nil
 	}
-	p.Lock()
-	if p.Track == nil {
-		p.State = PlayerStateStopped
-	} else {
-		p.State = PlayerStatePaused
+	if err := p.checkTransition(PlayerStatePaused, PlayerStatePlaying); err != nil {
+		return err
 	}
-	p.Unlock()
-	data, err := json.Marshal(playerPausePayload{
-		Op:      "pause",
-		GuildID: p.GuildID,
-		Pause:   true,
-	})
+	msg, err := p.codec().EncodePause(p.GuildID, true)
 	if err != nil {
 		return err
 	}
-	return p.socket.Send(data)
+	err = p.send(msg)
+	if err != nil {
+		return err
+	}
+	p.setState(PlayerStatePaused)
+	return nil
 }
 
-// Resume the current track if any is playing.
+// Resume the current track if any is paused. Does nothing if the player is already playing.
 func (p *Player) Resume() error {
-	if p.State == PlayerStateNone {
-		return errors.New("player's current state is set to None. Please make sure Player is connected to a voice channel")
+	if p.currentState() == PlayerStatePlaying {
+		return nil
 	}
-	p.Lock()
-	if p.Track == nil {
-		p.State = PlayerStateStopped
-	} else {
-		p.State = PlayerStatePlaying
+	if err := p.checkTransition(PlayerStatePlaying, PlayerStatePaused); err != nil {
+		return err
 	}
-	p.Unlock()
-	data, err := json.Marshal(playerPausePayload{
-		Op:      "pause",
-		GuildID: p.GuildID,
-		Pause:   false,
-	})
+	msg, err := p.codec().EncodePause(p.GuildID, false)
+	if err != nil {
+		return err
+	}
+	err = p.send(msg)
 	if err != nil {
 		return err
 	}
-	return p.socket.Send(data)
+	p.setState(PlayerStatePlaying)
+	return nil
 }
 
-// Skips the current track after the specified delay.
-func (p *Player) Skip(delay time.Duration) (skipped *Track, current *Track, err error) {
-	if p.State == PlayerStateNone {
-		return nil, nil, errors.New("player's current state is set to None. Please make sure Player is connected to a voice channel")
+// Skips the current track and plays the next one in the queue, stopping the player if the queue is empty.
+// The queue is only popped if the next track starts playing.
+func (p *Player) SkipNow() (skipped *Track, current *Track, err error) {
+	p.skipMu.Lock()
+	defer p.skipMu.Unlock()
+	if p.currentState() == PlayerStateNone {
+		return nil, nil, ErrPlayerNotConnected
 	}
-	p.Lock()
+	p.RLock()
 	skipped = p.Track
-	currentI, exists := p.Queue.Get(0)
+	p.RUnlock()
+	current, exists := p.Queue.Pop()
 	if !exists {
-		p.Unlock()
 		return skipped, nil, p.Stop()
 	}
-	p.Queue.Remove(0)
-	p.Unlock()
-	current = currentI.(*Track)
-	if delay != 0 {
-		time.Sleep(delay)
-	}
 	err = p.PlayTrack(current)
-	return
+	if err != nil {
+		p.Queue.Insert(0, current)
+		return skipped, nil, err
+	}
+	return skipped, current, nil
+}
+
+// Plays the track at the given queue index, dropping every track before it.
+func (p *Player) JumpTo(index int) (*Track, error) {
+	return p.jumpTo(index, false)
 }
 
-// Seeks the current track to specified position in milliseconds.
-func (p *Player) Seek(position int) error {
-	if p.State == PlayerStateNone {
-		return errors.New("player's current state is set to None. Please make sure Player is connected to a voice channel")
+// Plays the track at the given queue index, keeping the tracks before it queued.
+func (p *Player) JumpToPreserving(index int) (*Track, error) {
+	return p.jumpTo(index, true)
+}
+
+func (p *Player) jumpTo(index int, preserve bool) (*Track, error) {
+	p.skipMu.Lock()
+	defer p.skipMu.Unlock()
+	if p.currentState() == PlayerStateNone {
+		return nil, ErrPlayerNotConnected
 	}
-	if position > p.Track.Info.Length {
-		return fmt.Errorf("value must not be higer than %v", p.Track.Info.Length)
+	// Not holding the queue's lock while playing, event handlers may use the queue.
+	track, exists := p.Queue.Get(index)
+	if !exists {
+		return nil, fmt.Errorf("queue index %v out of range", index)
 	}
-	data, err := json.Marshal(playerSeekPayload{
-		Op:       "seek",
-		GuildID:  p.GuildID,
-		Position: position,
+	err := p.PlayTrack(track)
+	if err != nil {
+		return nil, err
+	}
+	q := p.Queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if index >= len(q.items) || q.items[index] != track {
+		// The queue changed meanwhile, find where the track went or leave the queue be if it's gone.
+		index = -1
+		for i, t := range q.items {
+			if t == track {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return track, nil
+		}
+	}
+	if preserve {
+		q.remove(index)
+	} else {
+		q.items = append(q.items[:0:0], q.items[index+1:]...)
+	}
+	return track, nil
+}
+
+// Schedules a skip after the specified delay without blocking, replacing any previously scheduled skip.
+// Calling cancel before the delay passes prevents the skip.
+func (p *Player) SkipAfter(delay time.Duration) (cancel func()) {
+	p.Lock()
+	defer p.Unlock()
+	if p.skipTimer != nil {
+		p.skipTimer.Stop()
+	}
+	var t *time.Timer
+	t = time.AfterFunc(delay, func() {
+		p.Lock()
+		if p.skipTimer != t {
+			p.Unlock()
+			return
+		}
+		p.skipTimer = nil
+		p.Unlock()
+		_, _, err := p.SkipNow()
+		if err != nil {
+			p.socket.ErrorReceived(err)
+		}
 	})
+	p.skipTimer = t
+	return func() {
+		p.Lock()
+		defer p.Unlock()
+		if p.skipTimer == t {
+			t.Stop()
+			p.skipTimer = nil
+		}
+	}
+}
+
+// Snapshot of what a player is currently playing.
+type NowPlaying struct {
+	// Track that is playing.
+	Track *Track
+	// Player's state.
+	State PlayerState
+	// Current position, extrapolated from the last update.
+	Position time.Duration
+	// Track's length, zero for streams.
+	Length time.Duration
+	// Time left until the track ends, zero for streams.
+	Remaining time.Duration
+	// How much of the track was played, from 0 to 100. Zero for streams.
+	Percentage float64
+	// Player's loop mode.
+	Loop LoopMode
+	// Player's current volume.
+	Volume int
+}
+
+// Returns what the player is currently playing, or nil if there's no track.
+func (p *Player) NowPlaying() *NowPlaying {
+	p.RLock()
+	defer p.RUnlock()
+	if p.Track == nil {
+		return nil
+	}
+	np := &NowPlaying{
+		Track:    p.Track,
+		State:    p.State,
+		Position: p.position(),
+		Length:   p.Track.duration(),
+		Loop:     p.loop,
+		Volume:   p.Volume,
+	}
+	if np.Length > 0 {
+		np.Remaining = np.Length - np.Position
+		np.Percentage = float64(np.Position) / float64(np.Length) * 100
+	}
+	return np
+}
+
+// Estimates how long until the track at the given queue index starts playing.
+// Streams, whose length is unknown, are counted as zero.
+func (p *Player) TimeUntil(index int) (time.Duration, error) {
+	if index < 0 || index >= p.Queue.Len() {
+		return 0, fmt.Errorf("queue index %v out of range", index)
+	}
+	var d time.Duration
+	p.RLock()
+	if p.Track != nil && p.State != PlayerStateStopped {
+		if length := p.Track.duration(); length > 0 {
+			d = length - p.position()
+		}
+	}
+	p.RUnlock()
+	return d + p.Queue.durationUntil(index), nil
+}
+
+// Seeks the current track to specified position.
+func (p *Player) Seek(position time.Duration) error {
+	p.RLock()
+	state, track := p.State, p.Track
+	p.RUnlock()
+	if state == PlayerStateNone {
+		return ErrPlayerNotConnected
+	}
+	if track == nil {
+		return errors.New("can't seek, no track is playing")
+	}
+	if position > track.Info.Length {
+		return fmt.Errorf("value must not be higer than %v", track.Info.Length)
+	}
+	msg, err := p.codec().EncodeSeek(p.GuildID, position)
+	if err != nil {
+		return err
+	}
+	err = p.send(msg)
 	if err != nil {
 		return err
 	}
-	return p.socket.Send(data)
+	p.updatePosition(position)
+	return nil
 }
 
 // Changes the current volume and updates p.Volume
 func (p *Player) UpdateVolume(volume int) error {
-	p.Lock()
-	p.Volume = volume
-	p.Unlock()
-	data, err := json.Marshal(playerVolumePayload{
-		Op:      "volume",
-		GuildID: p.GuildID,
-		Volume:  volume,
-	})
+	msg, err := p.codec().EncodeVolume(p.GuildID, volume)
+	if err != nil {
+		return err
+	}
+	err = p.send(msg)
 	if err != nil {
 		return err
 	}
-	return p.socket.Send(data)
+	p.Lock()
+	p.Volume = volume
+	p.Unlock()
+	return nil
 }
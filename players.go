@@ -0,0 +1,139 @@
+package lavago
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// Player as Lavalink v4 sees it, see `Node.FetchPlayers`.
+type RemotePlayer struct {
+	GuildID string `json:"guildId"`
+	// Nil if nothing is playing.
+	Track   *Track            `json:"-"`
+	Volume  int               `json:"volume"`
+	Paused  bool              `json:"paused"`
+	State   PlayerUpdateState `json:"state"`
+	Filters Filters           `json:"filters"`
+	Voice   RemoteVoiceState  `json:"voice"`
+}
+
+// Discord voice credentials Lavalink holds for a player.
+type RemoteVoiceState struct {
+	Token     string `json:"token"`
+	Endpoint  string `json:"endpoint"`
+	SessionID string `json:"sessionId"`
+}
+
+type remotePlayerJSON RemotePlayer
+
+// Decodes the track separately so a null track leaves Track nil.
+func (rp *RemotePlayer) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*remotePlayerJSON
+		Track json.RawMessage `json:"track"`
+	}{remotePlayerJSON: (*remotePlayerJSON)(rp)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	rp.Track = nil
+	if len(aux.Track) == 0 || string(aux.Track) == "null" {
+		return nil
+	}
+	t := &Track{}
+	if err := json.Unmarshal(aux.Track, t); err != nil {
+		return err
+	}
+	rp.Track = t
+	return nil
+}
+
+// Fetches every player of the node's Lavalink v4 session.
+func (n *Node) FetchPlayers(ctx context.Context) ([]*RemotePlayer, error) {
+	sessionID := n.SessionID()
+	if sessionID == "" {
+		return nil, ErrNoSession
+	}
+	var players []*RemotePlayer
+	err := n.getContext(ctx, "/v4/sessions/"+sessionID+"/players", &players)
+	if err != nil {
+		return nil, err
+	}
+	return players, nil
+}
+
+// Fetches the guild's player from the node's Lavalink v4 session.
+func (n *Node) FetchPlayer(ctx context.Context, guildID string) (*RemotePlayer, error) {
+	sessionID := n.SessionID()
+	if sessionID == "" {
+		return nil, ErrNoSession
+	}
+	rp := &RemotePlayer{}
+	err := n.getContext(ctx, "/v4/sessions/"+sessionID+"/players/"+url.PathEscape(guildID), rp)
+	if err != nil {
+		return nil, err
+	}
+	return rp, nil
+}
+
+// Overwrites the local players' track, position, pause state, volume and filters with what
+// Lavalink reports, i.e. after resuming or when they seem to have drifted. Local players
+// Lavalink doesn't know are stopped.
+func (n *Node) SyncPlayers(ctx context.Context) error {
+	remote, err := n.FetchPlayers(ctx)
+	if err != nil {
+		return err
+	}
+	byGuild := make(map[string]*RemotePlayer, len(remote))
+	for _, rp := range remote {
+		byGuild[rp.GuildID] = rp
+	}
+	n.players.Range(func(_, v interface{}) bool {
+		v.(*Player).reconcile(byGuild[v.(*Player).GuildID])
+		return true
+	})
+	return nil
+}
+
+// Applies the server's view of the player, rp is nil if the server has none.
+func (p *Player) reconcile(rp *RemotePlayer) {
+	p.Lock()
+	from := p.State
+	to := from
+	switch {
+	case rp == nil || rp.Track == nil:
+		p.Track, p.replaced = nil, nil
+		if from != PlayerStateNone {
+			to = PlayerStateStopped
+		}
+	default:
+		// Keep the local track when it's the same one, it carries the requester and other local data.
+		if p.Track == nil || p.Track.Track != rp.Track.Track {
+			if p.replaced != nil && p.replaced.Track == rp.Track.Track {
+				p.Track = p.replaced
+			} else {
+				p.Track = rp.Track
+			}
+			p.replaced = nil
+		}
+		p.Track.updatePosition(rp.State.Position)
+		to = PlayerStatePlaying
+		if rp.Paused {
+			to = PlayerStatePaused
+		}
+	}
+	if rp != nil {
+		p.Volume = rp.Volume
+		p.filters = rp.Filters.clone()
+		p.voiceConnected = rp.State.Connected
+		p.voicePing = rp.State.Ping
+		if rp.State.Time > 0 {
+			p.LastUpdate = time.UnixMilli(rp.State.Time)
+		}
+	}
+	p.positionAt = time.Now()
+	p.State = to
+	p.Unlock()
+	p.emitStateChanged(from, to)
+}
@@ -0,0 +1,238 @@
+package lavago
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+)
+
+// playerUpdate arrives every few seconds for every player, so it's parsed without
+// encoding/json to avoid its reflection and allocations. Anything the fast path doesn't
+// understand, like escaped strings, falls back to the regular decoding.
+
+var playerUpdateOp = []byte(`"op":"playerUpdate"`)
+
+// Parses a playerUpdate message. ok is false if data isn't one or can't be parsed by the fast path.
+// guildID aliases data.
+func parsePlayerUpdate(data []byte) (guildID []byte, state PlayerUpdateState, ok bool) {
+	if !bytes.Contains(data, playerUpdateOp) {
+		return nil, state, false
+	}
+	s := jsonScanner{data: data}
+	isUpdate := false
+	ok = s.object(func(key []byte) bool {
+		switch string(key) {
+		case "op":
+			// The op may also appear in a nested object, only the top level one counts.
+			op, valid := s.str()
+			isUpdate = valid && string(op) == "playerUpdate"
+			return valid
+		case "guildId":
+			var valid bool
+			guildID, valid = s.str()
+			return valid
+		case "state":
+			return s.object(func(key []byte) bool {
+				var valid bool
+				switch string(key) {
+				case "position":
+					var ms float64
+					ms, valid = s.number()
+					state.Position = time.Duration(ms * float64(time.Millisecond))
+				case "time":
+					var ms float64
+					ms, valid = s.number()
+					state.Time = int64(ms)
+				case "connected":
+					state.Connected, valid = s.boolean()
+				case "ping":
+					var ms float64
+					ms, valid = s.number()
+					state.Ping = time.Duration(ms * float64(time.Millisecond))
+				default:
+					valid = s.skip()
+				}
+				return valid
+			})
+		}
+		return s.skip()
+	})
+	s.ws()
+	return guildID, state, ok && isUpdate && guildID != nil && s.i == len(data)
+}
+
+// Minimal JSON reader over a byte slice for the fast paths of hot messages.
+type jsonScanner struct {
+	data []byte
+	i    int
+}
+
+func (s *jsonScanner) ws() {
+	for s.i < len(s.data) {
+		switch s.data[s.i] {
+		case ' ', '\t', '\n', '\r':
+			s.i++
+		default:
+			return
+		}
+	}
+}
+
+func (s *jsonScanner) consume(c byte) bool {
+	s.ws()
+	if s.i < len(s.data) && s.data[s.i] == c {
+		s.i++
+		return true
+	}
+	return false
+}
+
+// Reads an object, calling field with each key and the scanner positioned at its value.
+// field must consume the value.
+func (s *jsonScanner) object(field func(key []byte) bool) bool {
+	if !s.consume('{') {
+		return false
+	}
+	if s.consume('}') {
+		return true
+	}
+	for {
+		key, ok := s.str()
+		if !ok || !s.consume(':') {
+			return false
+		}
+		s.ws()
+		if !field(key) {
+			return false
+		}
+		if s.consume(',') {
+			continue
+		}
+		return s.consume('}')
+	}
+}
+
+// Reads a string without escape sequences, returning a slice of the input.
+func (s *jsonScanner) str() ([]byte, bool) {
+	if !s.consume('"') {
+		return nil, false
+	}
+	start := s.i
+	for s.i < len(s.data) {
+		switch s.data[s.i] {
+		case '"':
+			s.i++
+			return s.data[start : s.i-1], true
+		case '\\':
+			return nil, false
+		}
+		s.i++
+	}
+	return nil, false
+}
+
+func (s *jsonScanner) number() (float64, bool) {
+	s.ws()
+	start := s.i
+	neg := s.i < len(s.data) && s.data[s.i] == '-'
+	if neg {
+		s.i++
+	}
+	var n int64
+	digits := 0
+	for s.i < len(s.data) && s.data[s.i] >= '0' && s.data[s.i] <= '9' && digits < 18 {
+		n = n*10 + int64(s.data[s.i]-'0')
+		s.i++
+		digits++
+	}
+	if digits > 0 && !s.inNumber() {
+		if neg {
+			n = -n
+		}
+		return float64(n), true
+	}
+	// Fractions, exponents and huge numbers are rare enough to take the slow path.
+	for s.inNumber() {
+		s.i++
+	}
+	f, err := strconv.ParseFloat(string(s.data[start:s.i]), 64)
+	return f, err == nil
+}
+
+func (s *jsonScanner) inNumber() bool {
+	if s.i >= len(s.data) {
+		return false
+	}
+	switch c := s.data[s.i]; {
+	case c >= '0' && c <= '9', c == '.', c == 'e', c == 'E', c == '+', c == '-':
+		return true
+	}
+	return false
+}
+
+func (s *jsonScanner) boolean() (bool, bool) {
+	s.ws()
+	switch {
+	case bytes.HasPrefix(s.data[s.i:], []byte("true")):
+		s.i += 4
+		return true, true
+	case bytes.HasPrefix(s.data[s.i:], []byte("false")):
+		s.i += 5
+		return false, true
+	}
+	return false, false
+}
+
+// Skips over any value.
+func (s *jsonScanner) skip() bool {
+	s.ws()
+	if s.i >= len(s.data) {
+		return false
+	}
+	switch s.data[s.i] {
+	case '"':
+		s.i++
+		for s.i < len(s.data) {
+			switch s.data[s.i] {
+			case '\\':
+				s.i += 2
+				continue
+			case '"':
+				s.i++
+				return true
+			}
+			s.i++
+		}
+		return false
+	case '{', '[':
+		depth := 0
+		for s.i < len(s.data) {
+			switch s.data[s.i] {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					s.i++
+					return true
+				}
+			case '"':
+				if !s.skip() {
+					return false
+				}
+				continue
+			}
+			s.i++
+		}
+		return false
+	}
+	start := s.i
+	for s.i < len(s.data) {
+		switch s.data[s.i] {
+		case ',', '}', ']', ' ', '\t', '\n', '\r':
+			return s.i > start
+		}
+		s.i++
+	}
+	return s.i > start
+}
@@ -0,0 +1,108 @@
+package lavago
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParsePlayerUpdate(t *testing.T) {
+	fromV3 := string(fixture(t, "v3", "playerUpdate"))
+	fromV4 := string(fixture(t, "v4", "playerUpdate"))
+	tests := []struct {
+		name string
+		data string
+		// Whether the fast path handles it, messages it rejects go through encoding/json.
+		ok bool
+	}{
+		{"v3", fromV3, true},
+		{"v4", fromV4, true},
+		{"reordered", `{"state":{"ping":50,"connected":true,"position":60000,"time":1500467109},"guildId":"1","op":"playerUpdate"}`, true},
+		{"whitespace", " {\n\t\"op\":\"playerUpdate\" ,\r\n \"guildId\" : \"1\" , \"state\" : { \"position\" : 1 } } \n", true},
+		// The op is looked for as is before scanning.
+		{"spaced op", `{"op" : "playerUpdate","guildId":"1","state":{"position":1}}`, false},
+		{"unknown fields", `{"op":"playerUpdate","extra":{"a":[1,"}",{"b":"\"]"}]},"guildId":"1","state":{"filters":{},"position":1}}`, true},
+		{"escaped unknown field", `{"op":"playerUpdate","note":"say \"hi\"","guildId":"1","state":{"position":1}}`, true},
+		{"fraction", `{"op":"playerUpdate","guildId":"1","state":{"position":1500.5}}`, true},
+		{"exponent", `{"op":"playerUpdate","guildId":"1","state":{"position":6e4}}`, true},
+		{"negative ping", `{"op":"playerUpdate","guildId":"1","state":{"connected":false,"ping":-1}}`, true},
+		{"huge number", `{"op":"playerUpdate","guildId":"1","state":{"time":12345678901234567890}}`, true},
+		{"no state", `{"op":"playerUpdate","guildId":"1"}`, true},
+		{"escaped guild", `{"op":"playerUpdate","guildId":"\u0031","state":{"position":1}}`, false},
+		{"no guild", `{"op":"playerUpdate","state":{"position":1}}`, false},
+		{"other op", `{"op":"stats","guildId":"1","state":{"position":1}}`, false},
+		{"nested op", `{"op":"event","track":{"op":"playerUpdate"},"guildId":"1","state":{"position":1}}`, false},
+		{"string position", `{"op":"playerUpdate","guildId":"1","state":{"position":"1"}}`, false},
+		{"string connected", `{"op":"playerUpdate","guildId":"1","state":{"connected":"yes"}}`, false},
+		{"truncated", `{"op":"playerUpdate","guildId":"1","state":{"position":1`, false},
+		{"unterminated string", `{"op":"playerUpdate","guildId":"1`, false},
+		{"missing colon", `{"op":"playerUpdate","guildId" "1"}`, false},
+		{"missing comma", `{"op":"playerUpdate" "guildId":"1"}`, false},
+		{"trailing data", `{"op":"playerUpdate","guildId":"1"}{}`, false},
+		{"array", `["op","playerUpdate"]`, false},
+		{"empty", ``, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guildID, state, ok := parsePlayerUpdate([]byte(tt.data))
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			want := struct {
+				GuildID string
+				PlayerUpdatedEvent
+			}{}
+			if err := json.Unmarshal([]byte(tt.data), &want); err != nil {
+				// encoding/json rejects integers beyond int64, the fast path reads them as floats.
+				if tt.name == "huge number" {
+					return
+				}
+				t.Fatal(err)
+			}
+			if string(guildID) != want.GuildID {
+				t.Errorf("guildID = %q, want %q", guildID, want.GuildID)
+			}
+			if state != want.State {
+				t.Errorf("state = %+v, want %+v", state, want.State)
+			}
+		})
+	}
+}
+
+func TestParsePlayerUpdateFixture(t *testing.T) {
+	guildID, state, ok := parsePlayerUpdate(fixture(t, "v4", "playerUpdate"))
+	if !ok {
+		t.Fatal("fixture not parsed")
+	}
+	want := PlayerUpdateState{Position: time.Minute, Time: 1500467109, Connected: true, Ping: 50 * time.Millisecond}
+	if string(guildID) != fixtureGuildID || state != want {
+		t.Errorf("got %s %+v, want %s %+v", guildID, state, fixtureGuildID, want)
+	}
+}
+
+func BenchmarkSocketDataReceived(b *testing.B) {
+	data := fixture(b, "v3", "playerUpdate")
+	n := fixtureNode(b, V3Codec{})
+	b.Run("scanner", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			n.socketDataReceived(data)
+		}
+	})
+	b.Run("encoding/json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			pu := struct {
+				GuildID string `json:"guildId"`
+				PlayerUpdatedEvent
+			}{}
+			if err := json.Unmarshal(data, &pu); err != nil {
+				b.Fatal(err)
+			}
+			n.playerUpdated(pu.GuildID, pu.State)
+		}
+	})
+}
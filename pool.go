@@ -0,0 +1,253 @@
+package lavago
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Returned when no node in a pool can take a new player.
+var ErrNoNodeAvailable = errors.New("no connected node with capacity available")
+
+// Spreads players over several nodes, placing new players on the least loaded
+// connected node that isn't overloaded or failing health checks.
+type Pool struct {
+	// Where guild to node assignments are kept. Defaults to a `MemoryAffinityStore`.
+	Affinity AffinityStore
+	// Picks nodes for guilds without an assignment. Defaults to a `LeastLoadedBalancer`.
+	Balancer Balancer
+	// Connected nodes Connect waits for. Defaults to 1 and is capped at the number of nodes.
+	Quorum int
+	// Fired with the outcome of every node Connect brings up, including the ones finishing
+	// after it returned.
+	NodeConnectFinished func(NodeConnectResult)
+	// Fired for every guild moved off a node that gave up reconnecting. The new player
+	// continues the lost track from its last position with the old queue.
+	PlayerFailedOver func(FailoverEvent)
+
+	nodes   []*Node
+	players *sync.Map // map[string(GuildID)]*Node
+	gateway GatewayBridge
+	mu      sync.RWMutex
+}
+
+// Creates a pool of already configured nodes.
+func NewPool(nodes ...*Node) *Pool {
+	pl := &Pool{
+		Affinity: NewMemoryAffinityStore(),
+		Balancer: LeastLoadedBalancer{},
+		nodes:    nodes,
+		players:  &sync.Map{},
+	}
+	for _, n := range nodes {
+		pl.watch(n)
+	}
+	return pl
+}
+
+// Adds a node to the pool.
+func (pl *Pool) AddNode(n *Node) {
+	pl.watch(n)
+	pl.mu.Lock()
+	pl.nodes = append(pl.nodes, n)
+	if pl.gateway != nil {
+		connectVia(n, pl.gateway)
+	}
+	pl.mu.Unlock()
+}
+
+// Nodes in the pool.
+func (pl *Pool) Nodes() []*Node {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	nodes := make([]*Node, len(pl.nodes))
+	copy(nodes, pl.nodes)
+	return nodes
+}
+
+// Outcome of connecting one of a pool's nodes.
+type NodeConnectResult struct {
+	Node *Node
+	// Nil if the node connected.
+	Err error
+}
+
+// Returned by `Pool.Connect` once too many nodes failed to reach the quorum.
+type QuorumError struct {
+	Quorum int
+	// Nodes that connected.
+	Ready int
+	// Outcome of every node.
+	Results []NodeConnectResult
+}
+
+func (e *QuorumError) Error() string {
+	msg := fmt.Sprintf("%d of %d required nodes connected", e.Ready, e.Quorum)
+	if err := e.Unwrap(); err != nil {
+		msg += ": " + err.Error()
+	}
+	return msg
+}
+
+// First error of a node that failed to connect.
+func (e *QuorumError) Unwrap() error {
+	for _, r := range e.Results {
+		if r.Err != nil {
+			return r.Err
+		}
+	}
+	return nil
+}
+
+// Connects every node in parallel and returns once `Pool.Quorum` of them are connected, with
+// the outcomes so far. The others keep connecting in the background, see
+// `Pool.NodeConnectFinished`. Nodes already connected count right away, nodes still connecting
+// or reconnecting on their own are left alone and fail with ErrAlreadyConnected. Returns a *QuorumError
+// once the quorum can't be reached anymore, or ctx's error if it's done first. ctx only limits
+// the wait, close nodes to stop them connecting.
+func (pl *Pool) Connect(ctx context.Context, userID, shardCount string) ([]NodeConnectResult, error) {
+	nodes := pl.Nodes()
+	if len(nodes) == 0 {
+		return nil, errors.New("can't connect pool without nodes")
+	}
+	quorum := pl.Quorum
+	if quorum <= 0 {
+		quorum = 1
+	}
+	if quorum > len(nodes) {
+		quorum = len(nodes)
+	}
+	// Buffered so nodes finishing after Connect returned don't block.
+	results := make(chan NodeConnectResult, len(nodes))
+	for _, n := range nodes {
+		n := n
+		go func() {
+			r := NodeConnectResult{Node: n}
+			if !n.IsConnected() {
+				r.Err = n.Connect(userID, shardCount)
+			}
+			if pl.NodeConnectFinished != nil {
+				pl.NodeConnectFinished(r)
+			}
+			results <- r
+		}()
+	}
+	var done []NodeConnectResult
+	ready := 0
+	for {
+		select {
+		case r := <-results:
+			done = append(done, r)
+			if r.Err == nil {
+				ready++
+			}
+			if ready >= quorum {
+				return done, nil
+			}
+			if ready+len(nodes)-len(done) < quorum {
+				return done, &QuorumError{Quorum: quorum, Ready: ready, Results: done}
+			}
+		case <-ctx.Done():
+			return done, ctx.Err()
+		}
+	}
+}
+
+// Returns the guild's existing player or joins the voice channel on the guild's assigned node,
+// or the best available one if it has none. Pinned guilds only ever join their pinned node.
+// If storing the assignment fails, the player is returned along with the error.
+func (pl *Pool) Join(guildID, voiceChannelID string) (*Player, error) {
+	if n := pl.nodeOf(guildID); n != nil {
+		if p := n.GetPlayer(guildID); p != nil {
+			return p, nil
+		}
+	}
+	return pl.join(guildID, voiceChannelID, map[*Node]bool{})
+}
+
+// Joins on the guild's assigned or the best node, skipping the ones in tried.
+func (pl *Pool) join(guildID, voiceChannelID string, tried map[*Node]bool) (*Player, error) {
+	for {
+		n, err := pl.assignedNode(guildID, tried)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			return nil, ErrNoNodeAvailable
+		}
+		// Stored before joining so voice events sent in response reach the node.
+		pl.players.Store(guildID, n)
+		p, err := n.Join(guildID, voiceChannelID)
+		if err != nil {
+			pl.players.Delete(guildID)
+		}
+		if errors.Is(err, ErrNodeOverloaded) {
+			tried[n] = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		err = pl.assign(guildID, n)
+		return p, err
+	}
+}
+
+// Destroys the guild's player on whichever node holds it.
+func (pl *Pool) Leave(guildID string) error {
+	n := pl.nodeOf(guildID)
+	if n == nil {
+		return nil
+	}
+	pl.players.Delete(guildID)
+	return n.Leave(guildID)
+}
+
+// Returns the guild's player, nil if it has none.
+func (pl *Pool) GetPlayer(guildID string) *Player {
+	n := pl.nodeOf(guildID)
+	if n == nil {
+		return nil
+	}
+	return n.GetPlayer(guildID)
+}
+
+// Records the guild's node, keeping existing pins.
+func (pl *Pool) assign(guildID string, n *Node) error {
+	a, exists, err := pl.Affinity.Load(guildID)
+	if err != nil {
+		return err
+	}
+	if exists && a.Pinned {
+		return nil
+	}
+	return pl.Affinity.Store(guildID, Affinity{NodeID: n.ID()})
+}
+
+func (pl *Pool) nodeOf(guildID string) *Node {
+	nI, exists := pl.players.Load(guildID)
+	if !exists {
+		return nil
+	}
+	return nI.(*Node)
+}
+
+// Lets the pool's balancer pick among the connected, healthy, non-overloaded nodes, skipping the ones in exclude.
+func (pl *Pool) bestNode(guildID string, exclude map[*Node]bool) *Node {
+	var candidates []*Node
+	all := pl.Nodes()
+	for _, n := range all {
+		if exclude[n] || !n.IsConnected() || n.Unhealthy() || n.Overloaded() {
+			continue
+		}
+		candidates = append(candidates, n)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	if sb, ok := pl.Balancer.(StableBalancer); ok {
+		return sb.PickStable(guildID, all, candidates)
+	}
+	return pl.Balancer.Pick(guildID, candidates)
+}
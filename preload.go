@@ -0,0 +1,121 @@
+package lavago
+
+import (
+	"time"
+)
+
+// Longest the preloader waits before re-checking the position, so seeks, pauses and
+// playerUpdate corrections are picked up.
+const preloadCheckInterval = time.Second
+
+// Watches the player's position and preloads the next queued track, see `SchedulerConfig.PreloadWindow`.
+func (n *Node) startPreloader(p *Player) {
+	window := n.config().Scheduler.PreloadWindow
+	ctx, cancel := n.lifecycle()
+	p.Lock()
+	p.stopPreload = cancel
+	p.Unlock()
+	n.socket.spawn(func() {
+		defer cancel()
+		// Track the next one was last prepared or sent for.
+		var prepared, sent *Track
+		for {
+			wait := preloadCheckInterval
+			p.RLock()
+			track, state, pos, loop := p.Track, p.State, p.position(), p.loop
+			p.RUnlock()
+			if track != nil && state == PlayerStatePlaying && !track.Info.IsStream && loop != LoopModeTrack && sent != track {
+				end := track.duration()
+				if track.EndTime > 0 && track.EndTime < end {
+					end = track.EndTime
+				}
+				left := end - pos
+				switch {
+				case left > window:
+					if d := left - window; d < wait {
+						wait = d
+					}
+				case prepared != track:
+					prepared = track
+					n.prepareNext(p)
+					wait = left
+				case left > 0:
+					wait = left
+				default:
+					sent = track
+					n.sendPreloaded(p)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	})
+}
+
+// Encodes the next queued track ahead of time. The encoding is kept by the player, queued
+// tracks belong to the caller and aren't modified.
+func (n *Node) prepareNext(p *Player) {
+	next, exists := p.Queue.Get(0)
+	if !exists {
+		return
+	}
+	encoded := EncodeTrack(next)
+	p.Lock()
+	p.prepared, p.preparedEncoded = next, encoded
+	p.Unlock()
+}
+
+// Encoded form of track, reusing the preloader's if it prepared this track.
+func (p *Player) encode(track *Track) string {
+	p.RLock()
+	prepared, encoded := p.prepared, p.preparedEncoded
+	p.RUnlock()
+	if prepared == track && encoded != "" {
+		return encoded
+	}
+	return EncodeTrack(track)
+}
+
+// Sends the next queued track without replacing the current one, leaving the queue and the
+// player's state to `Node.advance` once the current track's end event arrives.
+func (n *Node) sendPreloaded(p *Player) {
+	next, exists := p.Queue.Get(0)
+	if !exists {
+		return
+	}
+	msg, err := n.codec().EncodePlay(p.GuildID, p.trackOp(next, true))
+	if err != nil {
+		n.socketOnError(err)
+		return
+	}
+	if err := p.send(msg); err != nil {
+		n.socketOnError(err)
+		return
+	}
+	p.addPending(next)
+	p.Lock()
+	p.preloaded = next
+	p.Unlock()
+}
+
+// Returns and forgets the track sent by the preloader.
+func (p *Player) takePreloaded() *Track {
+	p.Lock()
+	defer p.Unlock()
+	t := p.preloaded
+	p.preloaded = nil
+	return t
+}
+
+func (p *Player) stopPreloader() {
+	p.Lock()
+	stop := p.stopPreload
+	p.stopPreload = nil
+	p.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
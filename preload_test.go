@@ -0,0 +1,42 @@
+package lavago
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPreloadSendsNextWithoutReplacing(t *testing.T) {
+	f := newFakeLavalink(t)
+	cfg := f.config(t)
+	cfg.Scheduler = SchedulerConfig{AutoAdvance: true, PreloadWindow: time.Second}
+	n := f.connect(t, cfg)
+	p := join(t, n)
+	next := &Track{Info: TrackInfo{
+		Identifier: "dQw4w9WgXcQ",
+		Title:      "Never Gonna Give You Up",
+		Author:     "Rick Astley",
+		Length:     212 * time.Second,
+		URL:        "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		SourceName: "youtube",
+	}}
+	p.Queue.Add(next)
+	if err := p.PlayTrack(&Track{Track: "current", Info: TrackInfo{Length: 300 * time.Millisecond}}); err != nil {
+		t.Fatal(err)
+	}
+	op := f.next(t, func(op map[string]interface{}) bool {
+		return op["op"] == "play" && op["noReplace"] == true
+	})
+	decoded, err := DecodeTrackString(op["track"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Info.Identifier != next.Info.Identifier {
+		t.Errorf("preloaded %q, want %q", decoded.Info.Identifier, next.Info.Identifier)
+	}
+	if next.Track != "" {
+		t.Error("preloader modified the queued track")
+	}
+	if p.Queue.Len() != 1 {
+		t.Errorf("queue length %v after preloading, want 1 until the current track ends", p.Queue.Len())
+	}
+}
@@ -0,0 +1,308 @@
+package lavago
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Ordered list of values waiting to be played. Safe for concurrent use.
+type Queue[T any] struct {
+	items []T
+	mu    sync.RWMutex
+}
+
+// Creates an empty queue.
+func NewQueue[T any]() *Queue[T] {
+	return &Queue[T]{}
+}
+
+// Appends values to the end of the queue.
+func (q *Queue[T]) Add(values ...T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items = append(q.items, values...)
+}
+
+// Inserts values at the given index, shifting the following ones back.
+// Indexes past the end append to the queue.
+func (q *Queue[T]) Insert(index int, values ...T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if index < 0 {
+		index = 0
+	}
+	if index > len(q.items) {
+		index = len(q.items)
+	}
+	items := make([]T, 0, len(q.items)+len(values))
+	items = append(items, q.items[:index]...)
+	items = append(items, values...)
+	q.items = append(items, q.items[index:]...)
+}
+
+// Returns the value at the given index.
+func (q *Queue[T]) Get(index int) (T, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if index < 0 || index >= len(q.items) {
+		var zero T
+		return zero, false
+	}
+	return q.items[index], true
+}
+
+// Removes the value at the given index.
+func (q *Queue[T]) Remove(index int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.remove(index)
+}
+
+// Must hold the lock.
+func (q *Queue[T]) remove(index int) {
+	if index < 0 || index >= len(q.items) {
+		return
+	}
+	var zero T
+	copy(q.items[index:], q.items[index+1:])
+	q.items[len(q.items)-1] = zero
+	q.items = q.items[:len(q.items)-1]
+}
+
+// Removes and returns the first value.
+func (q *Queue[T]) Pop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	v := q.items[0]
+	q.remove(0)
+	return v, true
+}
+
+// Removes every value.
+func (q *Queue[T]) Clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.items = nil
+}
+
+// Number of values in the queue.
+func (q *Queue[T]) Len() int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return len(q.items)
+}
+
+// Copy of the queued values in play order.
+func (q *Queue[T]) Values() []T {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	values := make([]T, len(q.items))
+	copy(values, q.items)
+	return values
+}
+
+// Calls fn for each value of a snapshot of the queue, stopping when fn returns false.
+// The queue may be modified from fn or other goroutines while iterating.
+func (q *Queue[T]) Each(fn func(index int, value T) bool) {
+	for i, v := range q.Values() {
+		if !fn(i, v) {
+			return
+		}
+	}
+}
+
+// Returns the index of the first value matching the predicate, or -1 if none does.
+func (q *Queue[T]) IndexOf(match func(T) bool) int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	for i, v := range q.items {
+		if match(v) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Removes values whose key already appeared earlier in the queue and returns how many were removed.
+func (q *Queue[T]) DedupeFunc(key func(T) string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	seen := map[string]bool{}
+	items := q.items[:0]
+	for _, v := range q.items {
+		k := key(v)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		items = append(items, v)
+	}
+	removed := len(q.items) - len(items)
+	var zero T
+	for i := len(items); i < len(q.items); i++ {
+		q.items[i] = zero
+	}
+	q.items = items
+	return removed
+}
+
+// Randomizes the order of the queued values.
+func (q *Queue[T]) Shuffle() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	newRand().Shuffle(len(q.items), func(i, j int) {
+		q.items[i], q.items[j] = q.items[j], q.items[i]
+	})
+}
+
+func newRand() *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+// Limits enforced by `TrackQueue.Enqueue`. Zero values mean no limit.
+type QueuePolicy struct {
+	// Maximum number of queued tracks.
+	MaxLength int
+	// Maximum number of queued tracks per requester.
+	MaxPerRequester int
+	// Maximum length of a single track. Streams are never rejected by this limit.
+	MaxTrackDuration time.Duration
+}
+
+// Specifies why a track wasn't added to the queue.
+type QueueRejectReason byte
+
+const (
+	// The queue already holds `QueuePolicy.MaxLength` tracks.
+	QueueFullReason QueueRejectReason = iota
+	// The requester already has `QueuePolicy.MaxPerRequester` tracks queued.
+	RequesterLimitReason
+	// The track is longer than `QueuePolicy.MaxTrackDuration`.
+	TrackTooLongReason
+)
+
+// Returned by `TrackQueue.Enqueue` when the policy rejects a track.
+type QueueRejectedError struct {
+	// Track that was rejected.
+	Track *Track
+	// Why it was rejected.
+	Reason QueueRejectReason
+	// The limit that was hit.
+	Policy QueuePolicy
+}
+
+func (e *QueueRejectedError) Error() string {
+	switch e.Reason {
+	case QueueFullReason:
+		return fmt.Sprintf("queue is full (max %v tracks)", e.Policy.MaxLength)
+	case RequesterLimitReason:
+		return fmt.Sprintf("requester already has %v tracks queued", e.Policy.MaxPerRequester)
+	case TrackTooLongReason:
+		return fmt.Sprintf("track is longer than %v", e.Policy.MaxTrackDuration)
+	}
+	return "track rejected by queue policy"
+}
+
+// Default queue of a `Player`.
+type TrackQueue struct {
+	Queue[*Track]
+	// Limits applied by Enqueue.
+	Policy QueuePolicy
+}
+
+// Creates an empty track queue.
+func NewTrackQueue() *TrackQueue {
+	return &TrackQueue{}
+}
+
+// Appends a track to the end of the queue if the queue's policy allows it,
+// otherwise returns a *QueueRejectedError.
+func (q *TrackQueue) Enqueue(track *Track) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pol := q.Policy
+	if pol.MaxLength > 0 && len(q.items) >= pol.MaxLength {
+		return &QueueRejectedError{Track: track, Reason: QueueFullReason, Policy: pol}
+	}
+	if pol.MaxTrackDuration > 0 && track.duration() > pol.MaxTrackDuration {
+		return &QueueRejectedError{Track: track, Reason: TrackTooLongReason, Policy: pol}
+	}
+	if pol.MaxPerRequester > 0 && track.Requester != "" {
+		count := 0
+		for _, t := range q.items {
+			if t.Requester == track.Requester {
+				count++
+			}
+		}
+		if count >= pol.MaxPerRequester {
+			return &QueueRejectedError{Track: track, Reason: RequesterLimitReason, Policy: pol}
+		}
+	}
+	q.items = append(q.items, track)
+	return nil
+}
+
+// Removes tracks whose identifier already appeared earlier in the queue and returns how many were removed.
+func (q *TrackQueue) Dedupe() int {
+	return q.DedupeFunc(func(t *Track) string {
+		return t.Info.Identifier
+	})
+}
+
+// Sum of the queued tracks' lengths. Streams are excluded as they have no length.
+func (q *TrackQueue) Duration() time.Duration {
+	return q.durationUntil(-1)
+}
+
+// Sum of the lengths of the tracks before index, or of all tracks if index is negative.
+func (q *TrackQueue) durationUntil(index int) time.Duration {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	var d time.Duration
+	for i, t := range q.items {
+		if i == index {
+			break
+		}
+		d += t.duration()
+	}
+	return d
+}
+
+// Shuffles the queue and interleaves requesters round-robin, so no single requester's tracks
+// take over the next stretch of the queue. Tracks without a requester are treated as one requester.
+func (q *TrackQueue) FairShuffle() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	r := newRand()
+	var order []string
+	groups := map[string][]*Track{}
+	for _, t := range q.items {
+		if _, exists := groups[t.Requester]; !exists {
+			order = append(order, t.Requester)
+		}
+		groups[t.Requester] = append(groups[t.Requester], t)
+	}
+	r.Shuffle(len(order), func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+	for _, g := range groups {
+		r.Shuffle(len(g), func(i, j int) {
+			g[i], g[j] = g[j], g[i]
+		})
+	}
+	items := make([]*Track, 0, len(q.items))
+	for round := 0; len(items) < len(q.items); round++ {
+		for _, requester := range order {
+			if g := groups[requester]; round < len(g) {
+				items = append(items, g[round])
+			}
+		}
+	}
+	q.items = items
+}
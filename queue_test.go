@@ -0,0 +1,113 @@
+package lavago
+
+import (
+	"testing"
+	"time"
+)
+
+func queued(names ...string) []*Track {
+	tracks := make([]*Track, len(names))
+	for i, name := range names {
+		tracks[i] = &Track{Track: name}
+	}
+	return tracks
+}
+
+func queueNames(q *TrackQueue) []string {
+	var names []string
+	for _, t := range q.Values() {
+		names = append(names, t.Track)
+	}
+	return names
+}
+
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestQueueIndexOf(t *testing.T) {
+	q := NewQueue[*Track]()
+	q.Add(queued("a", "b", "c")...)
+	if i := q.IndexOf(func(t *Track) bool { return t.Track == "b" }); i != 1 {
+		t.Errorf("IndexOf(b) = %v, want 1", i)
+	}
+	if i := q.IndexOf(func(t *Track) bool { return t.Track == "z" }); i != -1 {
+		t.Errorf("IndexOf(z) = %v, want -1", i)
+	}
+}
+
+func TestJumpTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		preserve bool
+		want     []string
+	}{
+		{"drop", false, []string{"d"}},
+		{"preserve", true, []string{"a", "b", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, p := newFakeLavalink(t).player(t)
+			p.Queue.Add(queued("a", "b", "c", "d")...)
+			jump := p.JumpTo
+			if tt.preserve {
+				jump = p.JumpToPreserving
+			}
+			track, err := jump(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if track.Track != "c" || p.Track != track {
+				t.Errorf("playing %v, want c", p.Track)
+			}
+			if got := queueNames(p.Queue); !equalNames(got, tt.want) {
+				t.Errorf("queue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJumpToOutOfRange(t *testing.T) {
+	_, p := newFakeLavalink(t).player(t)
+	p.Queue.Add(queued("a")...)
+	if _, err := p.JumpTo(1); err == nil {
+		t.Fatal("JumpTo(1) on a single track queue succeeded")
+	}
+	if p.Queue.Len() != 1 {
+		t.Errorf("queue length %v after failed jump, want 1", p.Queue.Len())
+	}
+}
+
+func TestJumpToWithHandlerUsingQueue(t *testing.T) {
+	n, p := newFakeLavalink(t).player(t)
+	p.Queue.Add(queued("a", "b", "c", "d")...)
+	n.PlayerStateChanged = func(e PlayerStateChangedEvent) {
+		if e.To == PlayerStatePlaying && e.Player.Queue.Len() == 4 {
+			e.Player.Queue.Remove(0)
+		}
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.JumpToPreserving(2)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("JumpTo deadlocked on a handler reading the queue")
+	}
+	if got, want := queueNames(p.Queue), []string{"b", "d"}; !equalNames(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+}
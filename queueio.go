@@ -0,0 +1,207 @@
+package lavago
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// File format used by `TrackQueue.Export` and `TrackQueue.Import`.
+type QueueFormat byte
+
+const (
+	// JSON document holding the encoded tracks along with their info and queue metadata.
+	// Imports play without any lookups.
+	QueueFormatJSON QueueFormat = iota
+	// Extended M3U playlist, also used for .m3u8 files. Only holds each track's URL, title
+	// and length, so imports load every URL again through `Node.ImportQueue`.
+	QueueFormatM3U
+)
+
+// Version of the JSON export schema.
+const queueExportVersion = 1
+
+type queueExport struct {
+	Version int                `json:"version"`
+	Tracks  []queueExportTrack `json:"tracks"`
+}
+
+type queueExportTrack struct {
+	Track     string    `json:"track"`
+	Info      TrackInfo `json:"info"`
+	Requester string    `json:"requester,omitempty"`
+	StartTime millis    `json:"startTime,omitempty"`
+	EndTime   millis    `json:"endTime,omitempty"`
+	Volume    int       `json:"volume,omitempty"`
+}
+
+// Writes the queued tracks to w in the given format.
+func (q *TrackQueue) Export(w io.Writer, format QueueFormat) error {
+	tracks := q.Values()
+	switch format {
+	case QueueFormatJSON:
+		doc := queueExport{Version: queueExportVersion, Tracks: make([]queueExportTrack, len(tracks))}
+		for i, t := range tracks {
+			encoded := t.Track
+			if encoded == "" {
+				encoded = EncodeTrack(t)
+			}
+			doc.Tracks[i] = queueExportTrack{
+				Track:     encoded,
+				Info:      t.Info,
+				Requester: t.Requester,
+				StartTime: millis(t.StartTime),
+				EndTime:   millis(t.EndTime),
+				Volume:    t.Volume,
+			}
+		}
+		return json.NewEncoder(w).Encode(doc)
+	case QueueFormatM3U:
+		bw := bufio.NewWriter(w)
+		bw.WriteString("#EXTM3U\n")
+		for _, t := range tracks {
+			length := -1
+			if !t.Info.IsStream {
+				length = int(t.Info.Length.Seconds())
+			}
+			title := t.Info.Title
+			if t.Info.Author != "" {
+				title = t.Info.Author + " - " + title
+			}
+			fmt.Fprintf(bw, "#EXTINF:%d,%s\n%s\n", length, strings.ReplaceAll(title, "\n", " "), t.Info.URL)
+		}
+		return bw.Flush()
+	}
+	return fmt.Errorf("unknown queue format %v", format)
+}
+
+// Reads tracks in the given format from r and enqueues them, stopping at the first track the
+// queue's policy rejects. Returns how many tracks were enqueued. M3U playlists can't be
+// played as is, import them with `Node.ImportQueue`.
+func (q *TrackQueue) Import(r io.Reader, format QueueFormat) (int, error) {
+	var tracks []*Track
+	var err error
+	switch format {
+	case QueueFormatJSON:
+		tracks, err = importJSON(r)
+	case QueueFormatM3U:
+		err = errors.New("m3u playlists hold no encoded tracks, import them with Node.ImportQueue")
+	default:
+		err = fmt.Errorf("unknown queue format %v", format)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return q.enqueueAll(tracks)
+}
+
+// Like `TrackQueue.Import`, but loads the URLs of M3U playlists through the node first. Entries
+// Lavalink couldn't load aren't enqueued and are returned in unresolved instead.
+func (n *Node) ImportQueue(ctx context.Context, q *TrackQueue, r io.Reader, format QueueFormat) (enqueued int, unresolved []LoadResult, err error) {
+	if format != QueueFormatM3U {
+		enqueued, err = q.Import(r, format)
+		return enqueued, nil, err
+	}
+	entries, err := importM3U(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	urls := make([]string, len(entries))
+	for i, t := range entries {
+		urls[i] = t.Info.URL
+	}
+	var tracks []*Track
+	for _, lr := range n.LoadMany(ctx, urls, importConcurrency) {
+		if lr.Err == nil {
+			var loaded []*Track
+			loaded, lr.Err = loadedTracks(lr.Result)
+			tracks = append(tracks, loaded...)
+		}
+		if lr.Err != nil {
+			unresolved = append(unresolved, lr)
+		}
+	}
+	enqueued, err = q.enqueueAll(tracks)
+	return enqueued, unresolved, err
+}
+
+// Playlist entries loaded at a time by `Node.ImportQueue`.
+const importConcurrency = 4
+
+func (q *TrackQueue) enqueueAll(tracks []*Track) (int, error) {
+	for i, t := range tracks {
+		if err := q.Enqueue(t); err != nil {
+			return i, err
+		}
+	}
+	return len(tracks), nil
+}
+
+func importJSON(r io.Reader) ([]*Track, error) {
+	doc := queueExport{}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != queueExportVersion {
+		return nil, fmt.Errorf("unsupported queue export version %v", doc.Version)
+	}
+	tracks := make([]*Track, len(doc.Tracks))
+	for i, et := range doc.Tracks {
+		if et.Track == "" {
+			return nil, errors.New("queue export has a track without its encoded track")
+		}
+		tracks[i] = &Track{
+			Track:     et.Track,
+			Info:      et.Info,
+			Requester: et.Requester,
+			StartTime: time.Duration(et.StartTime),
+			EndTime:   time.Duration(et.EndTime),
+			Volume:    et.Volume,
+		}
+	}
+	return tracks, nil
+}
+
+func importM3U(r io.Reader) ([]*Track, error) {
+	var tracks []*Track
+	var info TrackInfo
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info = TrackInfo{}
+			meta := strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)
+			if secs, err := strconv.Atoi(strings.TrimSpace(meta[0])); err == nil {
+				if secs < 0 {
+					info.IsStream = true
+				} else {
+					info.Length = time.Duration(secs) * time.Second
+				}
+			}
+			if len(meta) == 2 {
+				info.Title = strings.TrimSpace(meta[1])
+				if author, title, found := strings.Cut(info.Title, " - "); found {
+					info.Author, info.Title = author, title
+				}
+			}
+		case strings.HasPrefix(line, "#"):
+			// Other directives and comments.
+		default:
+			info.URL = line
+			if info.Title == "" {
+				info.Title = line
+			}
+			tracks = append(tracks, &Track{Info: info})
+			info = TrackInfo{}
+		}
+	}
+	return tracks, sc.Err()
+}
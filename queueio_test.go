@@ -0,0 +1,75 @@
+package lavago
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestImportQueueM3U(t *testing.T) {
+	loads := map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ": "loadTrack",
+		"https://example.com/missing":                 "loadEmpty",
+		"https://example.com/broken":                  "loadFailed",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(fixture(t, "v3", loads[r.URL.Query().Get("identifier")]))
+	}))
+	defer srv.Close()
+	cfg := NewConfig()
+	cfg.Endpoints = []string{strings.TrimPrefix(srv.URL, "http://")}
+	n, err := NewNode(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playlist := "#EXTM3U\n" +
+		"#EXTINF:212,Rick Astley - Never Gonna Give You Up\nhttps://www.youtube.com/watch?v=dQw4w9WgXcQ\n" +
+		"#EXTINF:-1,Missing\nhttps://example.com/missing\n" +
+		"#EXTINF:10,Broken\nhttps://example.com/broken\n"
+	q := NewTrackQueue()
+	if _, err := q.Import(strings.NewReader(playlist), QueueFormatM3U); err == nil {
+		t.Error("TrackQueue.Import enqueued an M3U playlist")
+	}
+	enqueued, unresolved, err := n.ImportQueue(context.Background(), q, strings.NewReader(playlist), QueueFormatM3U)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enqueued != 1 || q.Len() != 1 {
+		t.Fatalf("enqueued %d, queue holds %d, want 1", enqueued, q.Len())
+	}
+	if track := q.Values()[0]; track.Track == "" {
+		t.Error("enqueued track has no encoded track")
+	}
+	if len(unresolved) != 2 {
+		t.Fatalf("%d unresolved entries, want 2", len(unresolved))
+	}
+	if unresolved[0].Identifier != "https://example.com/missing" || unresolved[0].Err != ErrNoResults {
+		t.Errorf("unresolved[0] = %s: %v, want missing: %v", unresolved[0].Identifier, unresolved[0].Err, ErrNoResults)
+	}
+	var se SearchException
+	if unresolved[1].Identifier != "https://example.com/broken" || !errors.As(unresolved[1].Err, &se) || se.Message == "" {
+		t.Errorf("unresolved[1] = %s: %v, want broken with Lavalink's exception", unresolved[1].Identifier, unresolved[1].Err)
+	}
+}
+
+func TestImportQueueJSON(t *testing.T) {
+	src := NewTrackQueue()
+	src.Enqueue(&Track{Track: "QAAAjQIAJVJpY2sgQXN0bGV5IC0gTmV2ZXIgR29ubmEgR2l2ZSBZb3UgVXAADlJpY2tBc3RsZXlWRVZPAAAAAAADPCAAC2RRdzR3OVdnWGNRAAEAK2h0dHBzOi8vd3d3LnlvdXR1YmUuY29tL3dhdGNoP3Y9ZFF3NHc5V2dYY1EAB3lvdXR1YmUAAAAAAAAAAA==", Requester: "1"})
+	buf := &bytes.Buffer{}
+	if err := src.Export(buf, QueueFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	q := NewTrackQueue()
+	enqueued, unresolved, err := fixtureNode(t, V3Codec{}).ImportQueue(context.Background(), q, buf, QueueFormatJSON)
+	if err != nil || enqueued != 1 || unresolved != nil {
+		t.Fatalf("got %d, %v, %v, want 1 track enqueued", enqueued, unresolved, err)
+	}
+	if got := q.Values()[0]; got.Requester != "1" {
+		t.Errorf("requester = %q, want 1", got.Requester)
+	}
+}
@@ -0,0 +1,53 @@
+package lavago
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Token bucket pacing REST requests, see `Config.RequestRate`. The rate is passed on every
+// call, so changes apply to the next request.
+type rateLimiter struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// Blocks until a request may be sent at rate requests per second with bursts of up to burst,
+// or ctx is done. A rate of zero never blocks.
+func (l *rateLimiter) wait(ctx context.Context, rate float64, burst int) error {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		if l.last.IsZero() {
+			l.tokens = float64(burst)
+		} else {
+			l.tokens += now.Sub(l.last).Seconds() * rate
+		}
+		if l.tokens > float64(burst) {
+			l.tokens = float64(burst)
+		}
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / rate * float64(time.Second))
+		l.mu.Unlock()
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
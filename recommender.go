@@ -0,0 +1,69 @@
+package lavago
+
+import (
+	"context"
+	"errors"
+)
+
+// Suggests tracks related to a seed track. Used by autoplay and `Player.Recommend`.
+type Recommender interface {
+	// Returns up to n tracks related to seed.
+	Recommend(ctx context.Context, seed *Track, n int) ([]*Track, error)
+}
+
+// Recommender based on the mixes Lavalink's source managers can load.
+// Seeds from sources without mixes are looked up on YouTube first.
+type MixRecommender struct {
+	node *Node
+}
+
+// Creates a recommender that loads mixes through the given node.
+func NewMixRecommender(node *Node) *MixRecommender {
+	return &MixRecommender{node: node}
+}
+
+func (r *MixRecommender) Recommend(ctx context.Context, seed *Track, n int) ([]*Track, error) {
+	if seed == nil {
+		return nil, errors.New("can't recommend without seed track")
+	}
+	id := seed.Info.Identifier
+	if seed.Info.SourceName != "youtube" {
+		sr, err := r.node.searchContext(ctx, YouTube, seed.Info.Author+" "+seed.Info.Title)
+		if err != nil {
+			return nil, err
+		}
+		if len(sr.Tracks) == 0 {
+			return nil, errors.New("no YouTube match for seed track")
+		}
+		id = sr.Tracks[0].Info.Identifier
+	}
+	sr, err := r.node.searchContext(ctx, Direct, "https://www.youtube.com/watch?v="+id+"&list=RD"+id)
+	if err != nil {
+		return nil, err
+	}
+	tracks := make([]*Track, 0, len(sr.Tracks))
+	for _, t := range sr.Tracks {
+		if t.Info.Identifier == seed.Info.Identifier {
+			continue
+		}
+		tracks = append(tracks, t)
+		if n > 0 && len(tracks) == n {
+			break
+		}
+	}
+	return tracks, nil
+}
+
+// Returns up to n tracks related to the current track using the node's recommender.
+func (p *Player) Recommend(ctx context.Context, n int) ([]*Track, error) {
+	if p.node == nil {
+		return nil, errors.New("can't recommend, player isn't attached to a node")
+	}
+	p.RLock()
+	seed := p.Track
+	p.RUnlock()
+	if seed == nil {
+		return nil, errors.New("can't recommend, no track is playing")
+	}
+	return p.node.Recommender.Recommend(ctx, seed, n)
+}
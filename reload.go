@@ -0,0 +1,93 @@
+package lavago
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Config shared by a node and its socket, replaced as a whole so readers never see a
+// half applied change.
+type configRef struct {
+	v atomic.Value
+}
+
+func newConfigRef(cfg *Config) *configRef {
+	ref := &configRef{}
+	ref.v.Store(cfg)
+	return ref
+}
+
+func (ref *configRef) load() *Config {
+	return ref.v.Load().(*Config)
+}
+
+func (n *Node) config() *Config {
+	return n.cfg.load()
+}
+
+// Node's current config. It must not be modified, use ApplyConfig instead.
+func (n *Node) Config() *Config {
+	return n.config()
+}
+
+// Replaces the node's config at runtime without dropping its connection or players. cfg is
+// copied, later changes to it have no effect.
+//
+// WriteTimeout, SendTimeout, the reconnect policy, UserAgent, RequestIDHeader, StrictDecoding,
+// Codec, Degradation, Overload, Scheduler, VoiceRecovery, DestroyOnKick, MoveOnJoin,
+// credentials, Logger, LogSeverity, RequestRate and RequestBurst apply right away, as does
+// HealthCheckInterval by restarting the health checks. Settings used when connecting, like
+// ExtraHeaders, ClientName, resuming and ResumeTimeout, PlayerUpdateInterval and
+// KeepAliveInterval, apply once the node reconnects. Player settings like HistorySize,
+// QueuePolicy and PositionTickInterval apply to players created afterwards.
+//
+// Name, Hostname, Port, Endpoints, SSL, TLS, Proxy, BufferSize, SendQueueSize and the REST
+// connection pool settings require a new node, ApplyConfig fails without applying anything if
+// they changed.
+func (n *Node) ApplyConfig(cfg *Config) error {
+	if cfg == nil {
+		return errors.New("can't apply nil Config")
+	}
+	if fields := fixedChanges(n.config(), cfg); len(fields) > 0 {
+		return fmt.Errorf("can't change %s without creating a new node", strings.Join(fields, ", "))
+	}
+	old := n.config()
+	c := *cfg
+	n.cfg.v.Store(&c)
+	if c.HealthCheckInterval != old.HealthCheckInterval {
+		n.mu.RLock()
+		ctx := n.ctx
+		n.mu.RUnlock()
+		if ctx != nil && ctx.Err() == nil {
+			n.startHealthChecks(ctx)
+		}
+	}
+	return nil
+}
+
+// Fields baked into the node's socket and REST client that differ between a and b.
+func fixedChanges(a, b *Config) []string {
+	var fields []string
+	changed := func(name string, differ bool) {
+		if differ {
+			fields = append(fields, name)
+		}
+	}
+	changed("Name", a.name() != b.name())
+	changed("Hostname", a.Hostname != b.Hostname)
+	changed("Port", a.Port != b.Port)
+	changed("Endpoints", strings.Join(a.Endpoints, ",") != strings.Join(b.Endpoints, ","))
+	changed("SSL", a.SSL != b.SSL)
+	changed("TLS", a.TLS != b.TLS)
+	changed("Proxy", (a.Proxy == nil) != (b.Proxy == nil) || (a.Proxy != nil && a.Proxy.String() != b.Proxy.String()))
+	changed("BufferSize", a.BufferSize != b.BufferSize)
+	changed("SendQueueSize", a.SendQueueSize != b.SendQueueSize)
+	changed("MaxIdleConns", a.MaxIdleConns != b.MaxIdleConns)
+	changed("MaxIdleConnsPerHost", a.MaxIdleConnsPerHost != b.MaxIdleConnsPerHost)
+	changed("IdleConnTimeout", a.IdleConnTimeout != b.IdleConnTimeout)
+	changed("DialTimeout", a.DialTimeout != b.DialTimeout)
+	changed("ForceHTTP2", a.ForceHTTP2 != b.ForceHTTP2)
+	return fields
+}
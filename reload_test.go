@@ -0,0 +1,95 @@
+package lavago
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLogger) logged() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+func TestApplyConfigLogSeverity(t *testing.T) {
+	logger := &recordingLogger{}
+	cfg := NewConfig()
+	cfg.Logger = logger
+	n, err := NewNode(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.ErrorReceived = func(ErrorEvent) {}
+	n.logf(LogDebug, "debug")
+	cfg = NewConfig()
+	cfg.Logger = logger
+	cfg.LogSeverity = LogWarning
+	if err := n.ApplyConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	n.logf(LogInfo, "info")
+	n.socketOnError(errors.New("boom"))
+	got := logger.logged()
+	if len(got) != 2 || !strings.HasSuffix(got[0], "debug") || !strings.HasSuffix(got[1], "boom") {
+		t.Errorf("logged %q, want the debug message and the error", got)
+	}
+}
+
+func TestApplyConfigRequestRate(t *testing.T) {
+	n := restNode(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("3.7.11"))
+	})
+	cfg := *n.Config()
+	cfg.RequestRate = 20
+	if err := n.ApplyConfig(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := n.Version(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first request goes out right away, the others wait 50ms each.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 requests at 20/s took %v", elapsed)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := n.Version(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("rate limited request with a cancelled context returned %v", err)
+	}
+}
+
+func TestApplyConfigRestartsHealthChecks(t *testing.T) {
+	n := newFakeLavalink(t).node(t)
+	cfg := *n.Config()
+	cfg.HealthCheckInterval = 10 * time.Millisecond
+	if err := n.ApplyConfig(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for n.Health().CheckedAt.IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("no health check ran after enabling them")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,140 @@
+package lavago
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Configures how players move through their queue when a track ends.
+type SchedulerConfig struct {
+	// Start the next queued track once the current one finishes or fails to load.
+	AutoAdvance bool
+	// Keep playing tracks from `Node.Recommender` once the queue runs out. Requires AutoAdvance.
+	// Can be changed per player with `Player.SetAutoplay`.
+	Autoplay bool
+	// How long before the current track ends to get the next queued track ready. At the
+	// expected end the next track is sent without replacing, so it starts as soon as Lavalink
+	// finishes the current one instead of after the track end event made the round trip.
+	// Zero disables preloading. Requires AutoAdvance.
+	PreloadWindow time.Duration
+}
+
+// Specifies what a player repeats once a track ends.
+type LoopMode byte
+
+const (
+	// Don't repeat anything.
+	LoopModeNone LoopMode = iota
+	// Repeat the current track.
+	LoopModeTrack
+	// Add finished tracks back to the end of the queue.
+	LoopModeQueue
+)
+
+// Information about the track autoplay picked once the queue ran out.
+type AutoplayTrackSelectedEvent struct {
+	// Player for which this event fired.
+	Player *Player
+	// Track the selection is based on.
+	Seed *Track
+	// Track that is now playing.
+	Track *Track
+}
+
+// Enables or disables autoplay for this player.
+func (p *Player) SetAutoplay(on bool) {
+	p.Lock()
+	p.autoplay = on
+	p.Unlock()
+}
+
+// Whether autoplay is enabled for this player.
+func (p *Player) Autoplay() bool {
+	p.RLock()
+	defer p.RUnlock()
+	return p.autoplay
+}
+
+// Sets what the player repeats once a track ends. Requires `SchedulerConfig.AutoAdvance`.
+func (p *Player) SetLoop(mode LoopMode) {
+	p.Lock()
+	p.loop = mode
+	p.Unlock()
+}
+
+// The player's loop mode.
+func (p *Player) Loop() LoopMode {
+	p.RLock()
+	defer p.RUnlock()
+	return p.loop
+}
+
+// Plays the next queued track, or a related one if the queue is empty and autoplay is on.
+func (n *Node) advance(p *Player, ended *Track) {
+	if !n.config().Scheduler.AutoAdvance {
+		return
+	}
+	switch p.Loop() {
+	case LoopModeTrack:
+		if ended != nil {
+			err := p.playTrack(ended, false, false)
+			if err != nil {
+				n.socketOnError(err)
+			}
+			return
+		}
+	case LoopModeQueue:
+		if ended != nil {
+			p.Queue.Add(ended)
+		}
+	}
+	next, exists := p.Queue.Pop()
+	if exists {
+		// A preloaded track may already be playing, only start it if Lavalink hasn't.
+		err := p.playTrack(next, true, p.takePreloaded() == next)
+		if err != nil {
+			p.Queue.Insert(0, next)
+			n.socketOnError(err)
+		}
+		return
+	}
+	if ended == nil || !p.Autoplay() {
+		return
+	}
+	track, err := n.autoplayTrack(p, ended)
+	if err != nil {
+		n.socketOnError(err)
+		return
+	}
+	err = p.PlayTrack(track)
+	if err != nil {
+		n.socketOnError(err)
+		return
+	}
+	if n.AutoplayTrackSelected == nil {
+		return
+	}
+	n.AutoplayTrackSelected(AutoplayTrackSelectedEvent{Player: p, Seed: ended, Track: track})
+}
+
+// How many related tracks autoplay asks the recommender for.
+const autoplayCandidates = 10
+
+// Picks a related track that isn't the seed and wasn't played recently.
+func (n *Node) autoplayTrack(p *Player, seed *Track) (*Track, error) {
+	related, err := n.Recommender.Recommend(context.Background(), seed, autoplayCandidates)
+	if err != nil {
+		return nil, err
+	}
+	played := map[string]bool{seed.Info.Identifier: true}
+	for _, t := range p.History() {
+		played[t.Info.Identifier] = true
+	}
+	for _, t := range related {
+		if !played[t.Info.Identifier] {
+			return t, nil
+		}
+	}
+	return nil, errors.New("autoplay found no related tracks")
+}
@@ -1,11 +1,178 @@
 package lavago
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+)
+
 // Lavalink's REST response.
 type SearchResult struct {
 	Status    SearchStatus    `json:"loadType,omitempty"`
 	Playlist  SearchPlaylist  `json:"playlistInfo,omitempty"`
 	Exception SearchException `json:"exception,omitempty"`
 	Tracks    []*Track        `json:"tracks,omitempty"`
+	// Undecoded tracks after this page, see NextPage.
+	rest  []json.RawMessage
+	limit int
+}
+
+// Limits the tracks of SEARCH_RESULT loads. Lavalink always returns every match at once,
+// so only the requested page is decoded and the rest is kept for `SearchResult.NextPage`.
+type SearchOptions struct {
+	// Tracks per page, zero returns all of them.
+	Limit int
+	// Matches skipped before the first page.
+	Offset int
+}
+
+// Returned by `SearchResult.NextPage` after the last page.
+var ErrNoMorePages = errors.New("no more search results")
+
+type searchResultJSON struct {
+	Status    SearchStatus      `json:"loadType,omitempty"`
+	Playlist  SearchPlaylist    `json:"playlistInfo,omitempty"`
+	Exception SearchException   `json:"exception,omitempty"`
+	Tracks    []json.RawMessage `json:"tracks,omitempty"`
+	// Lavalink v4's tracks, playlist or exception, depending on the load type.
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Lavalink v4's load types.
+var v4LoadTypes = map[SearchStatus]SearchStatus{
+	"track":    TrackLoadedSearchStatus,
+	"playlist": PlaylistLoadedSearchStatus,
+	"search":   SearchResultSearchStatus,
+	"empty":    NoMatchesSearchStatus,
+	"error":    LoadFailedSearchStatus,
+}
+
+// Moves a Lavalink v4 response's data into the fields Lavalink v3 uses.
+func (raw *searchResultJSON) normalize() error {
+	status, ok := v4LoadTypes[raw.Status]
+	if !ok {
+		return nil
+	}
+	raw.Status = status
+	if len(raw.Data) == 0 || string(raw.Data) == "null" {
+		return nil
+	}
+	switch status {
+	case TrackLoadedSearchStatus:
+		raw.Tracks = []json.RawMessage{raw.Data}
+	case SearchResultSearchStatus:
+		return json.Unmarshal(raw.Data, &raw.Tracks)
+	case PlaylistLoadedSearchStatus:
+		pl := struct {
+			Info   SearchPlaylist    `json:"info"`
+			Tracks []json.RawMessage `json:"tracks"`
+		}{}
+		if err := json.Unmarshal(raw.Data, &pl); err != nil {
+			return err
+		}
+		raw.Playlist, raw.Tracks = pl.Info, pl.Tracks
+	case LoadFailedSearchStatus:
+		return json.Unmarshal(raw.Data, &raw.Exception)
+	}
+	return nil
+}
+
+// Accepts both Lavalink v3 and v4 responses.
+func (sr *SearchResult) UnmarshalJSON(data []byte) error {
+	raw := searchResultJSON{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if err := raw.normalize(); err != nil {
+		return err
+	}
+	*sr = SearchResult{
+		Status:    raw.Status,
+		Playlist:  raw.Playlist,
+		Exception: raw.Exception,
+	}
+	return sr.decodePage(raw.Tracks)
+}
+
+// Like Search, but pages search results according to opts. Other load types return every track.
+func (n *Node) SearchWithOptions(stype SearchType, query string, opts SearchOptions) (*SearchResult, error) {
+	if opts.Limit < 0 || opts.Offset < 0 {
+		return nil, errors.New("search limit and offset can't be negative")
+	}
+	urlPath, err := n.searchPath(stype, query)
+	if err != nil {
+		return nil, err
+	}
+	raw := searchResultJSON{}
+	err = n.get(urlPath, &raw)
+	if err != nil {
+		return nil, err
+	}
+	err = raw.normalize()
+	if err != nil {
+		return nil, err
+	}
+	sr := &SearchResult{
+		Status:    raw.Status,
+		Playlist:  raw.Playlist,
+		Exception: raw.Exception,
+	}
+	tracks := raw.Tracks
+	if raw.Status == SearchResultSearchStatus {
+		if opts.Offset > len(tracks) {
+			opts.Offset = len(tracks)
+		}
+		tracks = tracks[opts.Offset:]
+		sr.limit = opts.Limit
+	}
+	err = sr.decodePage(tracks)
+	if err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
+
+// Whether NextPage has more tracks to return.
+func (sr *SearchResult) HasNextPage() bool {
+	return len(sr.rest) > 0
+}
+
+// Decodes the tracks following this page, using the same limit. The result itself isn't changed.
+// Returns ErrNoMorePages once every track was returned.
+func (sr *SearchResult) NextPage() (*SearchResult, error) {
+	if len(sr.rest) == 0 {
+		return nil, ErrNoMorePages
+	}
+	next := &SearchResult{
+		Status:    sr.Status,
+		Playlist:  sr.Playlist,
+		Exception: sr.Exception,
+		limit:     sr.limit,
+	}
+	err := next.decodePage(sr.rest)
+	if err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// Decodes up to limit tracks and keeps the others for NextPage.
+func (sr *SearchResult) decodePage(tracks []json.RawMessage) error {
+	page := tracks
+	if sr.limit > 0 && len(page) > sr.limit {
+		page, sr.rest = tracks[:sr.limit], tracks[sr.limit:]
+	}
+	sr.Tracks = make([]*Track, 0, len(page))
+	for _, data := range page {
+		t := &Track{}
+		if err := json.Unmarshal(data, t); err != nil {
+			return err
+		}
+		sr.Tracks = append(sr.Tracks, t)
+	}
+	return nil
 }
 
 // Search status when searching for songs via Lavalink.
@@ -32,8 +199,20 @@ type SearchPlaylist struct {
 
 // If SearchStatus was LoadFailed then Exception is returned.
 type SearchException struct {
-	Message  string `json:"message,omitempty"`
-	Severity string `json:"severity,omitempty"`
+	Message  string   `json:"message,omitempty"`
+	Severity Severity `json:"severity,omitempty"`
+}
+
+// Describes the failed load, Lavalink doesn't always send a message.
+func (e SearchException) Error() string {
+	msg := "lavalink failed to load tracks"
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Severity != "" {
+		msg += " (" + strings.ToLower(string(e.Severity)) + ")"
+	}
+	return msg
 }
 
 type SearchType byte
@@ -43,4 +222,55 @@ const (
 	YouTubeMusic
 	SoundCloud
 	Direct
+	// Searches `Config.DefaultSearchSource`.
+	DefaultSearch
 )
+
+// Outcome of loading one identifier with `Node.LoadMany`.
+type LoadResult struct {
+	Identifier string
+	// Nil if Err is set.
+	Result *SearchResult
+	Err    error
+}
+
+// Loads every identifier as is, i.e. URLs or "ytsearch:" queries, running up to concurrency
+// requests at a time. Results are in the same order as identifiers, and identifiers that
+// weren't loaded before ctx was done fail with its error.
+func (n *Node) LoadMany(ctx context.Context, identifiers []string, concurrency int) []LoadResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]LoadResult, len(identifiers))
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for i, identifier := range identifiers {
+		results[i].Identifier = identifier
+		if identifier == "" {
+			results[i].Err = errors.New("can't load empty identifier")
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(lr *LoadResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			sr := &SearchResult{}
+			err := n.getContext(ctx, n.loadPath(lr.Identifier), sr)
+			if err != nil {
+				lr.Err = err
+				return
+			}
+			lr.Result = sr
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
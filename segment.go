@@ -0,0 +1,107 @@
+package lavago
+
+import (
+	"errors"
+	"time"
+)
+
+// Longest the segment loop waits before re-checking the position, so seeks, pauses and
+// playerUpdate corrections are picked up.
+const segmentCheckInterval = time.Second
+
+// Repeats the part of the current track between start and end by seeking back to start
+// whenever the extrapolated position reaches end, replacing any previous segment loop.
+// Seeks to start right away if the position is outside the segment. The loop stops once
+// another track plays or ClearSegmentLoop is called.
+func (p *Player) LoopSegment(start, end time.Duration) error {
+	if start < 0 || end <= start {
+		return errors.New("segment must start at or after 0 and end after its start")
+	}
+	if p.node == nil {
+		return errors.New("can't loop a segment without a node")
+	}
+	p.RLock()
+	track := p.Track
+	pos := p.position()
+	p.RUnlock()
+	if track == nil {
+		return errors.New("can't loop a segment, no track is playing")
+	}
+	if track.Info.IsStream {
+		return errors.New("can't loop a segment of a stream")
+	}
+	if end > track.Info.Length {
+		return errors.New("segment can't end after the track")
+	}
+	p.ClearSegmentLoop()
+	if pos < start || pos >= end {
+		if err := p.Seek(start); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := p.node.lifecycle()
+	seg := &segmentLoop{start: start, end: end, stop: cancel}
+	p.Lock()
+	p.segment = seg
+	p.Unlock()
+	p.node.socket.spawn(func() {
+		defer cancel()
+		for {
+			p.RLock()
+			current, state, pos := p.Track, p.State, p.position()
+			p.RUnlock()
+			if current != track {
+				p.Lock()
+				if p.segment == seg {
+					p.segment = nil
+				}
+				p.Unlock()
+				return
+			}
+			wait := segmentCheckInterval
+			if state == PlayerStatePlaying {
+				if pos < end {
+					if left := end - pos; left < wait {
+						wait = left
+					}
+				} else if err := p.Seek(start); err != nil {
+					p.socket.ErrorReceived(err)
+				} else {
+					continue
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	})
+	return nil
+}
+
+// Stops the segment loop started by LoopSegment, playback continues past the segment's end.
+func (p *Player) ClearSegmentLoop() {
+	p.Lock()
+	seg := p.segment
+	p.segment = nil
+	p.Unlock()
+	if seg != nil {
+		seg.stop()
+	}
+}
+
+// Bounds of the active segment loop, ok is false if there is none.
+func (p *Player) SegmentLoop() (start, end time.Duration, ok bool) {
+	p.RLock()
+	defer p.RUnlock()
+	if p.segment == nil {
+		return 0, 0, false
+	}
+	return p.segment.start, p.segment.end, true
+}
+
+type segmentLoop struct {
+	start, end time.Duration
+	stop       func()
+}
@@ -0,0 +1,99 @@
+package lavago
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Returned by session requests on nodes without a Lavalink v4 session.
+var ErrNoSession = errors.New("node has no Lavalink v4 session")
+
+// Session settings changed through Lavalink v4's session endpoint. Nil fields are left unchanged.
+type SessionUpdate struct {
+	// Whether the session can be resumed after the websocket disconnects.
+	Resuming *bool
+	// How long Lavalink keeps the session around for resuming, in whole seconds.
+	ResumeTimeout *time.Duration
+	// How often Lavalink sends playerUpdate messages, in whole seconds. Stock Lavalink only
+	// reads this from its application.yml, so it's only honored by servers that accept it
+	// per session.
+	PlayerUpdateInterval *time.Duration
+}
+
+type sessionUpdatePayload struct {
+	Resuming             *bool  `json:"resuming,omitempty"`
+	Timeout              *int64 `json:"timeout,omitempty"`
+	PlayerUpdateInterval *int64 `json:"playerUpdateInterval,omitempty"`
+}
+
+func seconds(d *time.Duration) *int64 {
+	if d == nil {
+		return nil
+	}
+	s := int64(d.Seconds())
+	return &s
+}
+
+// Changes the session's settings on Lavalink v4.
+func (n *Node) UpdateSession(u SessionUpdate) error {
+	sessionID := n.SessionID()
+	if sessionID == "" {
+		return ErrNoSession
+	}
+	res, err := n.request("PATCH", "/v4/sessions/"+sessionID, sessionUpdatePayload{
+		Resuming:             u.Resuming,
+		Timeout:              seconds(u.ResumeTimeout),
+		PlayerUpdateInterval: seconds(u.PlayerUpdateInterval),
+	})
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Turns resuming on or off and sets how long Lavalink keeps the session around after a disconnect.
+// Uses the session endpoint on Lavalink v4 and the configureResuming op on v3, where the session
+// is resumed with `Config.ResumeKey`.
+func (n *Node) ConfigureResuming(enabled bool, timeout time.Duration) error {
+	if n.APIVersion() >= 4 {
+		return n.UpdateSession(SessionUpdate{Resuming: &enabled, ResumeTimeout: &timeout})
+	}
+	key := ""
+	if enabled {
+		key = n.config().ResumeKey
+	}
+	return n.sendConfigureResuming(key, timeout)
+}
+
+// Sends Lavalink v3's configureResuming op, an empty key disables resuming.
+func (n *Node) sendConfigureResuming(key string, timeout time.Duration) error {
+	data, err := json.Marshal(resumePayload{
+		Op:      "configureResuming",
+		Key:     key,
+		Timeout: int(timeout.Seconds()),
+	})
+	if err != nil {
+		return err
+	}
+	return n.socket.Send(data)
+}
+
+// Applies `Config.EnableResume` and `Config.PlayerUpdateInterval` once a v4 session is ready.
+func (n *Node) configureSession() {
+	u := SessionUpdate{}
+	if n.config().EnableResume {
+		enabled, timeout := true, n.config().ResumeTimeout
+		u.Resuming, u.ResumeTimeout = &enabled, &timeout
+	}
+	if n.config().PlayerUpdateInterval > 0 {
+		interval := n.config().PlayerUpdateInterval
+		u.PlayerUpdateInterval = &interval
+	}
+	if u == (SessionUpdate{}) {
+		return
+	}
+	if err := n.UpdateSession(u); err != nil {
+		n.socketOnError(err)
+	}
+}
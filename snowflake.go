@@ -0,0 +1,140 @@
+package lavago
+
+import (
+	"context"
+	"strconv"
+)
+
+// Discord IDs as distinct types, so swapped arguments like Join(channelID, guildID) fail to
+// compile. Only `TypedNode` and `TypedPool` take them, the string based API stays as is and
+// String converts back to it.
+type (
+	GuildID   string
+	ChannelID string
+	UserID    string
+)
+
+func (id GuildID) String() string   { return string(id) }
+func (id ChannelID) String() string { return string(id) }
+func (id UserID) String() string    { return string(id) }
+
+// Whether id looks like a snowflake, a non-empty unsigned integer.
+func (id GuildID) Valid() bool   { return validSnowflake(string(id)) }
+func (id ChannelID) Valid() bool { return validSnowflake(string(id)) }
+func (id UserID) Valid() bool    { return validSnowflake(string(id)) }
+
+func validSnowflake(id string) bool {
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil
+}
+
+// View of a node taking typed IDs, see `Node.Typed`.
+type TypedNode struct {
+	Node *Node
+}
+
+// The node's API with typed IDs. Both can be used side by side.
+func (n *Node) Typed() TypedNode {
+	return TypedNode{Node: n}
+}
+
+// See `Node.Connect`.
+func (t TypedNode) Connect(userID UserID, shardCount int) error {
+	return t.Node.Connect(string(userID), strconv.Itoa(shardCount))
+}
+
+// See `Node.Join`.
+func (t TypedNode) Join(guildID GuildID, channelID ChannelID) (*Player, error) {
+	return t.Node.Join(string(guildID), string(channelID))
+}
+
+// See `Node.JoinOrGet`.
+func (t TypedNode) JoinOrGet(guildID GuildID, channelID ChannelID) (*Player, error) {
+	return t.Node.JoinOrGet(string(guildID), string(channelID))
+}
+
+func (t TypedNode) Leave(guildID GuildID) error {
+	return t.Node.Leave(string(guildID))
+}
+
+func (t TypedNode) HasPlayer(guildID GuildID) bool {
+	return t.Node.HasPlayer(string(guildID))
+}
+
+func (t TypedNode) GetPlayer(guildID GuildID) *Player {
+	return t.Node.GetPlayer(string(guildID))
+}
+
+// See `Node.FetchPlayer`.
+func (t TypedNode) FetchPlayer(ctx context.Context, guildID GuildID) (*RemotePlayer, error) {
+	return t.Node.FetchPlayer(ctx, string(guildID))
+}
+
+// See `Node.Guild`.
+func (t TypedNode) Guild(guildID GuildID) *GuildHandle {
+	return t.Node.Guild(string(guildID))
+}
+
+// See `Node.OnVoiceStateUpdateChannel`, channelID is empty if the user left voice.
+func (t TypedNode) OnVoiceStateUpdate(shardUserID, userID UserID, guildID GuildID, channelID ChannelID, sessionID string) {
+	t.Node.OnVoiceStateUpdateChannel(string(shardUserID), string(userID), string(guildID), string(channelID), sessionID)
+}
+
+// See `Node.OnVoiceServerUpdate`.
+func (t TypedNode) OnVoiceServerUpdate(guildID GuildID, endpoint, token string) {
+	t.Node.OnVoiceServerUpdate(string(guildID), endpoint, token)
+}
+
+// View of a pool taking typed IDs, see `Pool.Typed`.
+type TypedPool struct {
+	Pool *Pool
+}
+
+// The pool's API with typed IDs. Both can be used side by side.
+func (pl *Pool) Typed() TypedPool {
+	return TypedPool{Pool: pl}
+}
+
+// See `Pool.Connect`.
+func (t TypedPool) Connect(ctx context.Context, userID UserID, shardCount int) ([]NodeConnectResult, error) {
+	return t.Pool.Connect(ctx, string(userID), strconv.Itoa(shardCount))
+}
+
+// See `Pool.Join`.
+func (t TypedPool) Join(guildID GuildID, channelID ChannelID) (*Player, error) {
+	return t.Pool.Join(string(guildID), string(channelID))
+}
+
+func (t TypedPool) Leave(guildID GuildID) error {
+	return t.Pool.Leave(string(guildID))
+}
+
+func (t TypedPool) GetPlayer(guildID GuildID) *Player {
+	return t.Pool.GetPlayer(string(guildID))
+}
+
+// See `Pool.PinGuild`.
+func (t TypedPool) PinGuild(guildID GuildID, nodeID string) error {
+	return t.Pool.PinGuild(string(guildID), nodeID)
+}
+
+func (t TypedPool) UnpinGuild(guildID GuildID) error {
+	return t.Pool.UnpinGuild(string(guildID))
+}
+
+// See `Pool.NodeFor`.
+func (t TypedPool) NodeFor(guildID GuildID) (*Node, error) {
+	return t.Pool.NodeFor(string(guildID))
+}
+
+// Typed IDs of a player.
+func (p *Player) IDs() (GuildID, ChannelID) {
+	p.RLock()
+	defer p.RUnlock()
+	return GuildID(p.GuildID), ChannelID(p.ChannelID)
+}
+
+// Like `GuildHandle.PlayQuery` with a typed requester.
+func (g *GuildHandle) PlayQueryAs(ctx context.Context, query string, requester UserID) (*PlayResult, error) {
+	return g.PlayQuery(ctx, query, string(requester))
+}
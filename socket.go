@@ -1,8 +1,11 @@
 package lavago
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"sync"
@@ -12,130 +15,531 @@ import (
 )
 
 type Socket struct {
-	cfg                *Config
+	cfg                *configRef
+	metrics            *socketMetrics
 	connectionAttempts int
-	reconnectInterval  time.Duration
-	dialer             *websocket.Dialer
-	conn               *websocket.Conn
-	connected          bool
-	sendChan           chan wsData
-	DataReceived       func([]byte)
-	OnOpen             func()
-	ErrorReceived      func(error)
+	// Index of the address in use, see `Config.Endpoints`.
+	endpoint  int
+	dialer    *websocket.Dialer
+	conn      *websocket.Conn
+	connected bool
+	// Response headers of the last successful handshake.
+	handshake http.Header
+	// Lavalink API version of the last successful handshake.
+	version int
+	// Whether the next dial uses Lavalink v3's websocket path, set once the v4 path wasn't found.
+	v3Path bool
+	// Cancelled when the connection is closed, stopping the socket's goroutines.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// Tracks every goroutine started by the socket.
+	wg       sync.WaitGroup
+	sendChan chan wsData
+	// Called with each text message. data is reused once the handler returns,
+	// handlers keeping it around must copy it.
+	DataReceived  func(data []byte)
+	OnOpen        func()
+	ErrorReceived func(error)
+	// Called with the read error when the connection was lost or closed by Lavalink, not when
+	// it's closed through Close.
+	OnClose func(error)
+	// Called when the handlers of received messages fall behind, see `Config.SlowConsumerThreshold`.
+	OnSlowConsumer func()
+	// Called with the address and error of every failed dial, and whether another is made.
+	OnConnectFailed func(addr string, err error, retry bool)
+	// Called before waiting delay to retry, attempt starts at 1.
+	OnRetry func(attempt, left int, delay time.Duration)
 	sync.RWMutex
 }
 
+// Returned when a message couldn't be queued and written within `Config.SendTimeout`.
+var ErrSendTimeout = errors.New("timed out sending websocket message")
+
+// Returned for messages still queued or sent while the socket is closing.
+var ErrSocketClosed = errors.New("websocket connection closed")
+
 type wsData struct {
 	data    []byte
 	errChan chan error
+	// Messages with the same non-empty key supersede each other when queued together.
+	key string
+}
+
+// Ops where only the newest queued message per guild matters.
+var coalescableOps = map[string]bool{
+	"pause":  true,
+	"seek":   true,
+	"volume": true,
+}
+
+// Returns the key used to coalesce data with later messages, empty if it must always be written.
+func coalesceKey(data []byte) string {
+	bp := basePayload{}
+	if json.Unmarshal(data, &bp) != nil || !coalescableOps[bp.Op] || bp.GuildID == "" {
+		return ""
+	}
+	return bp.Op + ":" + bp.GuildID
 }
 
 func NewSocket(cfg *Config) *Socket {
 	s := &Socket{
-		cfg: cfg,
-		// reconnectInterval: cfg.ReconnectDelay, start at 0 because we add up duration everytime we call Connect(), ie. on each retry we += cfg.ReconnectDelay
+		cfg:     newConfigRef(cfg),
+		metrics: &socketMetrics{},
 		dialer: &websocket.Dialer{
 			ReadBufferSize:   cfg.BufferSize,
 			WriteBufferSize:  cfg.BufferSize,
-			Proxy:            http.ProxyFromEnvironment,
+			Proxy:            cfg.proxy(),
 			HandshakeTimeout: 45 * time.Second,
+			TLSClientConfig:  cfg.TLS,
 		},
-		sendChan:      make(chan wsData),
-		DataReceived:  func(b []byte) {},
-		OnOpen:        func() {},
-		ErrorReceived: func(err error) {},
+		sendChan:        make(chan wsData, cfg.SendQueueSize),
+		DataReceived:    func(b []byte) {},
+		OnOpen:          func() {},
+		ErrorReceived:   func(err error) {},
+		OnClose:         func(err error) {},
+		OnSlowConsumer:  func() {},
+		OnConnectFailed: func(addr string, err error, retry bool) {},
+		OnRetry:         func(attempt, left int, delay time.Duration) {},
 	}
 
 	return s
 }
 
+func (s *Socket) config() *Config {
+	return s.cfg.load()
+}
+
 func (s *Socket) Connect(headers http.Header) error {
-	if s.conn != nil {
+	return s.connect(context.Background(), headers)
+}
+
+// Dials Lavalink and starts the socket's goroutines, which stop once ctx is cancelled or the socket is closed.
+func (s *Socket) connect(ctx context.Context, headers http.Header) error {
+	s.RLock()
+	open := s.conn != nil
+	s.RUnlock()
+	if open {
 		return errors.New("websocket is already in open state")
 	}
-	conn, res, err := s.dialer.Dial(s.cfg.socketEndpoint(), headers)
+	addr := s.address()
+	s.RLock()
+	v3Path := s.v3Path
+	s.RUnlock()
+	path := "/v4/websocket"
+	if v3Path {
+		path = "/"
+	}
+	conn, res, err := s.dialer.DialContext(ctx, s.config().socketEndpoint(addr)+path, headers)
+	if err != nil && res != nil && res.StatusCode == http.StatusNotFound {
+		// Lavalink v3 only serves the websocket on its root path, v4 only on /v4/websocket.
+		s.Lock()
+		s.v3Path = !v3Path
+		s.Unlock()
+		if !v3Path {
+			return s.connect(ctx, headers)
+		}
+	}
 	if err != nil {
-		if s.connectionAttempts < s.cfg.ReconnectAttempts {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// Fail over to the next address right away, the delay only applies once all failed.
+		if s.nextEndpoint() {
+			s.OnConnectFailed(addr, err, true)
+			return s.connect(ctx, headers)
+		}
+		attempts := s.config().ReconnectAttempts
+		if s.connectionAttempts < attempts {
+			s.OnConnectFailed(addr, err, true)
 			s.connectionAttempts++
-			s.reconnectInterval += s.cfg.ReconnectDelay
-			time.Sleep(s.reconnectInterval)
-			return s.Connect(headers)
+			delay := s.config().backoff().Delay(s.connectionAttempts)
+			s.OnRetry(s.connectionAttempts, attempts-s.connectionAttempts, delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return s.connect(ctx, headers)
 		}
+		s.connectionAttempts = 0
+		s.OnConnectFailed(addr, err, false)
 		return err
 	}
-	lverS := res.Header.Get("Lavalink-Api-Version")
-	lver, err := strconv.Atoi(lverS)
-	if err != nil {
+	version := handshakeVersion(res.Header, v3Path)
+	if version != 3 && version != 4 {
+		conn.Close()
+		err = fmt.Errorf("this version of lavago only supports Lavalink v3 and v4, not v%d", version)
+		s.OnConnectFailed(addr, err, false)
 		return err
 	}
-	if lver != 3 {
-		return errors.New("this version of lavago only supports Lavalink v3.x")
-	}
+	s.Lock()
 	s.conn = conn
+	s.handshake = res.Header
+	s.version = version
+	s.ctx, s.cancel = context.WithCancel(ctx)
 	s.connected = true
-	go s.sendListener()
-	go s.readListener()
-	go s.OnOpen()
+	s.connectionAttempts = 0
+	s.Unlock()
+	s.spawn(func() { s.sendListener(s.ctx, conn) })
+	s.spawn(func() { s.readListener(s.ctx, conn) })
+	if interval := s.config().KeepAliveInterval; interval > 0 {
+		s.spawn(func() { s.keepAlive(s.ctx, conn, interval) })
+	}
+	s.spawn(s.OnOpen)
 	return nil
 }
 
-func (s *Socket) sendListener() {
-	for data := range s.sendChan {
-		data.errChan <- s.conn.WriteMessage(websocket.TextMessage, data.data)
+// Address the socket connects to, REST requests use the same one.
+func (s *Socket) address() string {
+	endpoints := s.config().endpoints()
+	s.RLock()
+	defer s.RUnlock()
+	return endpoints[s.endpoint%len(endpoints)]
+}
+
+// Moves on to the next address. Reports false and starts over with the first one once all
+// were tried.
+func (s *Socket) nextEndpoint() bool {
+	n := len(s.config().endpoints())
+	s.Lock()
+	defer s.Unlock()
+	if s.endpoint+1 < n {
+		s.endpoint++
+		return true
+	}
+	s.endpoint = 0
+	return false
+}
+
+// Runs fn in a goroutine tracked by Wait.
+func (s *Socket) spawn(fn func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fn()
+	}()
+}
+
+// Blocks until every goroutine started by the socket returned, including event handlers.
+// Must not be called from an event handler.
+func (s *Socket) Wait() {
+	s.wg.Wait()
+}
+
+// Response headers of the last successful handshake.
+func (s *Socket) handshakeHeader() http.Header {
+	s.RLock()
+	defer s.RUnlock()
+	return s.handshake
+}
+
+// Lavalink API version of the last successful handshake, zero before the first.
+func (s *Socket) apiVersion() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.version
+}
+
+// API version reported in the handshake's headers, or implied by the websocket path when the
+// server sends neither.
+func handshakeVersion(h http.Header, v3Path bool) int {
+	for _, name := range []string{"Lavalink-Api-Version", "Lavalink-Major-Version"} {
+		if v, err := strconv.Atoi(h.Get(name)); err == nil {
+			return v
+		}
+	}
+	if v3Path {
+		return 3
+	}
+	return 4
+}
+
+func (s *Socket) isConnected() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.connected
+}
+
+// Whether the current connection was closed, or there is none.
+func (s *Socket) closed() bool {
+	select {
+	case <-s.done():
+		return true
+	default:
+		return false
+	}
+}
+
+// Done channel of the current connection, closed if there is none.
+func (s *Socket) done() <-chan struct{} {
+	s.RLock()
+	defer s.RUnlock()
+	return s.ctx.Done()
+}
+
+func (s *Socket) sendListener(ctx context.Context, conn *websocket.Conn) {
+	for {
+		var batch []wsData
+		select {
+		case <-ctx.Done():
+			s.failPending()
+			return
+		case data := <-s.sendChan:
+			batch = append(batch, data)
+		}
+	drain:
+		for {
+			select {
+			case d := <-s.sendChan:
+				batch = append(batch, d)
+			default:
+				break drain
+			}
+		}
+		s.writeBatch(conn, batch)
+	}
+}
+
+// Pings Lavalink every interval until the connection is closed. Pings are control frames,
+// which may be written alongside the sendListener.
+func (s *Socket) keepAlive(ctx context.Context, conn *websocket.Conn, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		timeout := s.config().WriteTimeout
+		if timeout <= 0 {
+			timeout = interval
+		}
+		// A dead connection also fails the readListener, which reports it.
+		if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(timeout)); err != nil {
+			return
+		}
 	}
 }
 
-func (s *Socket) readListener() {
+// Fails the messages left in the queue once the connection is closed.
+func (s *Socket) failPending() {
 	for {
-		msgType, data, err := s.conn.ReadMessage()
-		if msgType == websocket.CloseMessage {
+		select {
+		case d := <-s.sendChan:
+			d.errChan <- ErrSocketClosed
+		default:
 			return
 		}
+	}
+}
+
+// Writes every queued message in order, skipping control messages superseded by a later one for the same guild.
+func (s *Socket) writeBatch(conn *websocket.Conn, batch []wsData) {
+	last := map[string]int{}
+	for i, d := range batch {
+		if d.key != "" {
+			last[d.key] = i
+		}
+	}
+	for i, d := range batch {
+		if d.key != "" && last[d.key] != i {
+			d.errChan <- nil
+			continue
+		}
+		if s.config().WriteTimeout > 0 {
+			conn.SetWriteDeadline(time.Now().Add(s.config().WriteTimeout))
+		}
+		err := conn.WriteMessage(websocket.TextMessage, d.data)
+		if err == nil {
+			s.metrics.sent(len(d.data))
+		}
+		d.errChan <- err
+	}
+}
+
+// Buffers messages are read into, returned once DataReceived is done with them.
+var readBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// Larger buffers aren't pooled so a single huge message doesn't stay in memory.
+const maxPooledReadBuffer = 64 << 10
+
+// Reads until the connection fails or is closed. Read errors are permanent, so unless the
+// socket is being closed the connection is torn down and OnClose is called.
+func (s *Socket) readListener(ctx context.Context, conn *websocket.Conn) {
+	for {
+		msgType, buf, err := readMessage(conn)
 		if err != nil {
-			go s.ErrorReceived(err)
+			s.Lock()
+			s.connected = false
+			lost := ctx.Err() == nil && s.conn == conn
+			if lost {
+				s.conn = nil
+				s.cancel()
+			}
+			s.Unlock()
+			if lost {
+				conn.Close()
+				s.spawn(func() { s.OnClose(err) })
+			}
+			return
 		}
-		if msgType == websocket.TextMessage {
-			go s.DataReceived(data)
+		if msgType != websocket.TextMessage {
+			releaseReadBuffer(buf)
+			continue
 		}
+		s.dispatch(buf)
+	}
+}
+
+// Hands a received message to DataReceived in its own goroutine, recording the dispatch.
+func (s *Socket) dispatch(buf *bytes.Buffer) {
+	s.metrics.received(buf.Len())
+	threshold := s.config().SlowConsumerThreshold
+	if s.metrics.enter(threshold) {
+		s.spawn(s.OnSlowConsumer)
 	}
+	read := time.Now()
+	s.spawn(func() {
+		s.metrics.dispatched(time.Since(read))
+		defer s.metrics.leave(threshold)
+		defer releaseReadBuffer(buf)
+		s.DataReceived(buf.Bytes())
+	})
+}
+
+// Reads the next message into a pooled buffer.
+func readMessage(conn *websocket.Conn) (int, *bytes.Buffer, error) {
+	msgType, r, err := conn.NextReader()
+	if err != nil {
+		return msgType, nil, err
+	}
+	buf := readBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	_, err = buf.ReadFrom(r)
+	if err != nil {
+		releaseReadBuffer(buf)
+		return msgType, nil, err
+	}
+	return msgType, buf, nil
+}
+
+func releaseReadBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledReadBuffer {
+		return
+	}
+	readBufferPool.Put(buf)
 }
 
 func (s *Socket) Send(data []byte) error {
-	if !s.connected {
+	if !s.isConnected() {
 		return errors.New("can't send, no connection open")
 	}
 	if len(data) == 0 {
 		return errors.New("can't send no data")
 	}
+	var timeout <-chan time.Time
+	if s.config().SendTimeout > 0 {
+		timer := time.NewTimer(s.config().SendTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	done := s.done()
+	errChan := make(chan error, 1)
+	select {
+	case s.sendChan <- wsData{data: data, errChan: errChan, key: coalesceKey(data)}:
+	case <-timeout:
+		return ErrSendTimeout
+	case <-done:
+		return ErrSocketClosed
+	}
+	select {
+	case err := <-errChan:
+		return err
+	case <-timeout:
+		return ErrSendTimeout
+	case <-done:
+		return ErrSocketClosed
+	}
+}
+
+// Queues data without waiting for it to be written. The returned channel receives the
+// write's result. Pause, seek and volume messages still queued when a newer one for the
+// same guild arrives are dropped and report success.
+func (s *Socket) SendAsync(data []byte) <-chan error {
+	// Buffered so the writer never blocks on a caller that stopped listening.
 	errChan := make(chan error, 1)
-	s.sendChan <- wsData{data, errChan}
-	err := <-errChan
-	close(errChan)
-	return err
+	if !s.isConnected() {
+		errChan <- errors.New("can't send, no connection open")
+		return errChan
+	}
+	if len(data) == 0 {
+		errChan <- errors.New("can't send no data")
+		return errChan
+	}
+	msg := wsData{data: data, errChan: errChan, key: coalesceKey(data)}
+	select {
+	case s.sendChan <- msg:
+		return errChan
+	default:
+	}
+	// The queue is full, wait for room without blocking the caller.
+	done := s.done()
+	s.spawn(func() {
+		var timeout <-chan time.Time
+		if s.config().SendTimeout > 0 {
+			timer := time.NewTimer(s.config().SendTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case s.sendChan <- msg:
+		case <-timeout:
+			errChan <- ErrSendTimeout
+		case <-done:
+			errChan <- ErrSocketClosed
+		}
+	})
+	return errChan
 }
 
 func (s *Socket) SendJSON(value interface{}) error {
-	if !s.connected {
+	if !s.isConnected() {
 		return errors.New("can't send, no connection open")
 	}
 	if value == nil {
 		return errors.New("can't send nil value")
 	}
-	errChan := make(chan error, 1)
 	data, err := json.Marshal(value)
 	if err != nil {
 		return err
 	}
-	s.sendChan <- wsData{data, errChan}
-	err = <-errChan
-	close(errChan)
-	return err
+	return s.Send(data)
 }
 
+// Closes the connection and stops the socket's goroutines. Use Wait to block until they returned.
 func (s *Socket) Close() error {
 	s.Lock()
+	conn := s.conn
+	s.conn = nil
 	s.connected = false
+	if s.cancel != nil {
+		s.cancel()
+	}
 	s.Unlock()
-	close(s.sendChan)
-	return s.conn.Close()
+	if conn == nil {
+		return errors.New("websocket is not open")
+	}
+	return conn.Close()
+}
+
+// Context that is already done, used while the socket has no connection.
+func closedContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
 }
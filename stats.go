@@ -0,0 +1,249 @@
+package lavago
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+)
+
+// Information about Lavalink statistics.
+type StatsReceivedEvent struct {
+	// Machine's CPU info.
+	CPU CPUStats `json:"cpu"`
+	// Audio frames, nil when no player is playing.
+	Frames *FrameStats `json:"frameStats,omitempty"`
+	// General memory information about Lavalink.
+	Memory MemoryStats `json:"memory"`
+	// Connected players.
+	Players int `json:"players"`
+	// Players that are currently playing.
+	PlayingPlayers int `json:"playingPlayers"`
+	// Lavalink uptime.
+	Uptime time.Duration `json:"uptime"`
+	// When the stats were received.
+	Time time.Time `json:"-"`
+}
+
+func (e *StatsReceivedEvent) UnmarshalJSON(data []byte) error {
+	type stats StatsReceivedEvent
+	aux := struct {
+		*stats
+		Uptime millis `json:"uptime"`
+	}{stats: (*stats)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	e.Uptime = time.Duration(aux.Uptime)
+	return nil
+}
+
+// CPU usage of the machine running Lavalink.
+type CPUStats struct {
+	Cores int `json:"cores"`
+	// Load of the whole system, from 0 to 1.
+	SystemLoad float64 `json:"systemLoad"`
+	// Load caused by Lavalink, from 0 to 1.
+	LavalinkLoad float64 `json:"lavalinkLoad"`
+}
+
+// Audio frames sent to Discord per player over the last minute.
+type FrameStats struct {
+	// Frames sent to Discord.
+	Sent int `json:"sent"`
+	// Frames that were nulled.
+	Nulled int `json:"nulled"`
+	// Frames missing compared to what should have been sent.
+	Deficit int `json:"deficit"`
+}
+
+// Memory usage of Lavalink in bytes.
+type MemoryStats struct {
+	Free       int64 `json:"free"`
+	Used       int64 `json:"used"`
+	Allocated  int64 `json:"allocated"`
+	Reservable int64 `json:"reservable"`
+}
+
+// Averages over the stats a node kept.
+type StatsAverages struct {
+	// Number of stats payloads averaged.
+	Samples int
+	// Average system CPU load, from 0 to 1.
+	SystemLoad float64
+	// Average CPU load caused by Lavalink, from 0 to 1.
+	LavalinkLoad float64
+	// Average nulled frames per minute.
+	FramesNulled float64
+	// Average frame deficit per minute.
+	FrameDeficit float64
+}
+
+func (n *Node) recordStats(sr StatsReceivedEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	size := n.config().StatsHistorySize
+	if size <= 0 {
+		size = 1
+	}
+	n.stats = append(n.stats, sr)
+	if over := len(n.stats) - size; over > 0 {
+		n.stats = append(n.stats[:0], n.stats[over:]...)
+	}
+}
+
+// Most recently received stats, false if none arrived yet.
+func (n *Node) Stats() (StatsReceivedEvent, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if len(n.stats) == 0 {
+		return StatsReceivedEvent{}, false
+	}
+	return n.stats[len(n.stats)-1], true
+}
+
+// Stats received within the configured window, oldest first.
+func (n *Node) StatsHistory() []StatsReceivedEvent {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	history := make([]StatsReceivedEvent, len(n.stats))
+	copy(history, n.stats)
+	return history
+}
+
+// Averages over the stats received within the configured window.
+func (n *Node) StatsAverages() StatsAverages {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	avg := StatsAverages{Samples: len(n.stats)}
+	if avg.Samples == 0 {
+		return avg
+	}
+	for _, sr := range n.stats {
+		avg.SystemLoad += sr.CPU.SystemLoad
+		avg.LavalinkLoad += sr.CPU.LavalinkLoad
+		if sr.Frames != nil {
+			avg.FramesNulled += float64(sr.Frames.Nulled)
+			avg.FrameDeficit += float64(sr.Frames.Deficit)
+		}
+	}
+	samples := float64(avg.Samples)
+	avg.SystemLoad /= samples
+	avg.LavalinkLoad /= samples
+	avg.FramesNulled /= samples
+	avg.FrameDeficit /= samples
+	return avg
+}
+
+// Returned when a node refuses new players because it exceeds its `OverloadThresholds`.
+var ErrNodeOverloaded = errors.New("node is overloaded")
+
+// Limits above which a node refuses new players. Zero values mean no limit.
+type OverloadThresholds struct {
+	// Maximum number of players on the Lavalink server, counting other clients' players.
+	MaxPlayers int
+	// Maximum average system CPU load, from 0 to 1.
+	MaxCPULoad float64
+	// Maximum average frame deficit per minute.
+	MaxFrameDeficit float64
+}
+
+// Whether the node exceeds its configured `OverloadThresholds`.
+func (n *Node) Overloaded() bool {
+	th := n.config().Overload
+	if th.MaxPlayers > 0 {
+		if sr, ok := n.Stats(); ok && sr.Players >= th.MaxPlayers {
+			return true
+		}
+	}
+	avg := n.StatsAverages()
+	if avg.Samples == 0 {
+		return false
+	}
+	if th.MaxCPULoad > 0 && avg.SystemLoad > th.MaxCPULoad {
+		return true
+	}
+	return th.MaxFrameDeficit > 0 && avg.FrameDeficit > th.MaxFrameDeficit
+}
+
+// Cost of adding a player to the node, lower is better. Combines playing players,
+// smoothed CPU load and frame losses the same way Lavalink's reference client does.
+func (n *Node) penalty() float64 {
+	sr, ok := n.Stats()
+	if !ok {
+		return 0
+	}
+	avg := n.StatsAverages()
+	penalty := float64(sr.PlayingPlayers)
+	penalty += math.Pow(1.05, 100*avg.SystemLoad)*10 - 10
+	if sr.Frames != nil {
+		penalty += math.Pow(1.03, 500*avg.FrameDeficit/3000)*600 - 600
+		penalty += (math.Pow(1.03, 500*avg.FramesNulled/3000)*300 - 300) * 2
+	}
+	return penalty
+}
+
+// When a node's audio counts as degraded. Zero values disable the check.
+type DegradationThresholds struct {
+	// Estimated share of lost audio frames, from 0 to 1.
+	MaxPacketLoss float64
+	// How many consecutive stats payloads must exceed MaxPacketLoss.
+	Samples int
+}
+
+// Information about a node sending noticeably fewer audio frames than it should.
+type NodeDegradedEvent struct {
+	// Node for which this event fired.
+	Node *Node
+	// Estimated share of lost audio frames over the last minute, from 0 to 1.
+	PacketLoss float64
+	// Frames nulled over the last minute.
+	FramesNulled int
+	// Frame deficit over the last minute.
+	FrameDeficit int
+}
+
+// Frames each playing player should send per minute.
+const framesPerMinute = 3000
+
+// Estimated share of audio frames lost according to the stats, from 0 to 1.
+func (sr StatsReceivedEvent) PacketLoss() float64 {
+	if sr.Frames == nil || sr.PlayingPlayers == 0 {
+		return 0
+	}
+	loss := float64(sr.Frames.Nulled+sr.Frames.Deficit) / float64(sr.PlayingPlayers*framesPerMinute)
+	return math.Max(0, math.Min(1, loss))
+}
+
+// Fires NodeDegraded once the last stats all exceed the packet loss threshold, and again only after recovering.
+func (n *Node) checkDegraded() {
+	th := n.config().Degradation
+	if th.MaxPacketLoss <= 0 || th.Samples <= 0 {
+		return
+	}
+	history := n.StatsHistory()
+	if len(history) < th.Samples {
+		return
+	}
+	degraded := true
+	for _, sr := range history[len(history)-th.Samples:] {
+		if sr.PacketLoss() <= th.MaxPacketLoss {
+			degraded = false
+			break
+		}
+	}
+	n.mu.Lock()
+	changed := degraded != n.degraded
+	n.degraded = degraded
+	n.mu.Unlock()
+	if !changed || !degraded || n.NodeDegraded == nil {
+		return
+	}
+	last := history[len(history)-1]
+	n.NodeDegraded(NodeDegradedEvent{
+		Node:         n,
+		PacketLoss:   last.PacketLoss(),
+		FramesNulled: last.Frames.Nulled,
+		FrameDeficit: last.Frames.Deficit,
+	})
+}
@@ -0,0 +1,25 @@
+package lavago
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStatsFixtures(t *testing.T) {
+	for _, version := range []string{"v3", "v4"} {
+		e := StatsReceivedEvent{}
+		if err := json.Unmarshal(fixture(t, version, "stats"), &e); err != nil {
+			t.Fatalf("%s: %v", version, err)
+		}
+		if e.Uptime != 123456789*time.Millisecond {
+			t.Errorf("%s: uptime %v", version, e.Uptime)
+		}
+		if e.Players != 1 || e.PlayingPlayers != 1 || e.CPU.Cores != 4 || e.Memory.Used != 123456789 {
+			t.Errorf("%s: got %+v", version, e)
+		}
+		if e.Frames == nil || *e.Frames != (FrameStats{Sent: 6000, Nulled: 10, Deficit: -3010}) {
+			t.Errorf("%s: frames %+v", version, e.Frames)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package lavago
+
+import (
+	"errors"
+	"time"
+)
+
+// Fired periodically while a player is playing, see `Player.StartPositionTicks`.
+type PositionTickEvent struct {
+	Player *Player
+	// Snapshot of the player when the tick fired, with the position extrapolated from the last update.
+	NowPlaying *NowPlaying
+}
+
+// Starts firing `Node.PositionTick` every interval while the player is playing, replacing
+// any previous ticker. Useful for progress bars or synced lyrics without running separate
+// timers. Started automatically for new players when `Config.PositionTickInterval` is set.
+func (p *Player) StartPositionTicks(interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("position tick interval must be positive")
+	}
+	if p.node == nil {
+		return errors.New("can't tick positions without a node")
+	}
+	p.StopPositionTicks()
+	ctx, cancel := p.node.lifecycle()
+	p.Lock()
+	p.stopTicks = cancel
+	p.Unlock()
+	p.node.socket.spawn(func() {
+		defer cancel()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			p.tick()
+		}
+	})
+	return nil
+}
+
+// Stops the ticker started by StartPositionTicks.
+func (p *Player) StopPositionTicks() {
+	p.Lock()
+	stop := p.stopTicks
+	p.stopTicks = nil
+	p.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+func (p *Player) tick() {
+	handler := p.node.PositionTick
+	if handler == nil {
+		return
+	}
+	np := p.NowPlaying()
+	if np == nil || np.State != PlayerStatePlaying {
+		return
+	}
+	handler(PositionTickEvent{Player: p, NowPlaying: np})
+}
@@ -1,10 +1,34 @@
 package lavago
 
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
 // Track information.
 type Track struct {
 	// Track's encoded hash.
 	Track string    `json:"track,omitempty"`
 	Info  TrackInfo `json:"info,omitempty"`
+	// ID of the user who requested the track. Never sent to Lavalink.
+	Requester string `json:"-"`
+	// Where to start and stop playing whenever the track is played from the queue or with
+	// `Player.PlayTrack`, i.e. to skip a long intro. Zero plays from the start or to the end.
+	StartTime time.Duration `json:"-"`
+	EndTime   time.Duration `json:"-"`
+	// Volume to play the track at, zero keeps the player's current volume.
+	Volume int `json:"-"`
+	// Chapters in order, from the track's plugin info or set by the application, see `ext/chapters`.
+	Chapters []Chapter `json:"-"`
+	// Anything the application wants to keep with the track, i.e. the metadata of the link it
+	// was resolved from. Never sent to Lavalink.
+	UserData interface{} `json:"-"`
 }
 
 type TrackInfo struct {
@@ -17,17 +41,156 @@ type TrackInfo struct {
 	// Whether the track is seekable.
 	CanSeek bool `json:"isSeekable,omitempty"`
 	// Track's length.
-	Length int `json:"length,omitempty"`
+	Length time.Duration `json:"length,omitempty"`
 	//  Whether the track is a stream.
-	IsStream bool `json:"is_stream,omitempty"`
+	IsStream bool `json:"isStream,omitempty"`
 	// Track's current position.
-	Position int `json:"position,omitempty"`
+	Position time.Duration `json:"position,omitempty"`
 	// Track's url.
 	URL string `json:"uri,omitempty"`
 	// Name of the source
 	SourceName string `json:"sourceName,omitempty"`
+	// Track's artwork url, only sent by Lavalink v4.
+	Artwork string `json:"artworkUrl,omitempty"`
+	// International Standard Recording Code, only sent by Lavalink v4 for some sources.
+	ISRC string `json:"isrc,omitempty"`
 }
 
-func (t *Track) updatePosition(pos int) {
+type trackInfoJSON TrackInfo
+
+// Lavalink sends Length and Position in milliseconds.
+func (ti TrackInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		trackInfoJSON
+		Length   millis `json:"length,omitempty"`
+		Position millis `json:"position,omitempty"`
+	}{trackInfoJSON(ti), millis(ti.Length), millis(ti.Position)})
+}
+
+func (ti *TrackInfo) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*trackInfoJSON
+		Length   millis `json:"length,omitempty"`
+		Position millis `json:"position,omitempty"`
+	}{trackInfoJSON: (*trackInfoJSON)(ti)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	ti.Length = time.Duration(aux.Length)
+	ti.Position = time.Duration(aux.Position)
+	return nil
+}
+
+func (t *Track) updatePosition(pos time.Duration) {
 	t.Info.Position = pos
 }
+
+// Track's length, zero for streams.
+func (t *Track) duration() time.Duration {
+	if t.Info.IsStream {
+		return 0
+	}
+	return t.Info.Length
+}
+
+// Url of the track's artwork. Uses the url sent by Lavalink when present, otherwise derives
+// the thumbnail of YouTube tracks from their identifier. Empty if none is known, SoundCloud
+// artwork can't be derived and needs `Track.ResolveArtworkURL`.
+func (t *Track) ArtworkURL() string {
+	if t.Info.Artwork != "" {
+		return t.Info.Artwork
+	}
+	if t.Info.SourceName == "youtube" && t.Info.Identifier != "" {
+		return "https://i.ytimg.com/vi/" + t.Info.Identifier + "/hqdefault.jpg"
+	}
+	return ""
+}
+
+// SoundCloud's oEmbed endpoint, which needs no client ID.
+var soundCloudOEmbedURL = "https://soundcloud.com/oembed"
+
+// Like ArtworkURL, but looks the artwork of SoundCloud tracks Lavalink sent none for up through
+// SoundCloud's oEmbed endpoint, since their artwork urls hold a hash the identifier lacks.
+func (t *Track) ResolveArtworkURL(ctx context.Context) (string, error) {
+	if artwork := t.ArtworkURL(); artwork != "" || t.Info.SourceName != "soundcloud" || t.Info.URL == "" {
+		return artwork, nil
+	}
+	q := url.Values{"format": {"json"}, "url": {t.Info.URL}}
+	req, err := http.NewRequestWithContext(ctx, "GET", soundCloudOEmbedURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("soundcloud responded with %v", res.Status)
+	}
+	oembed := struct {
+		ThumbnailURL string `json:"thumbnail_url"`
+	}{}
+	err = json.NewDecoder(res.Body).Decode(&oembed)
+	if err != nil {
+		return "", err
+	}
+	return oembed.ThumbnailURL, nil
+}
+
+// How far apart two track lengths may be for `Track.Matches` to consider them the same recording.
+const matchLengthTolerance = 3 * time.Second
+
+// Whether both tracks are the same recording, possibly from different sources.
+// Compares ISRCs when both are known, then identifiers on the same source,
+// then falls back to a fuzzy title match with similar lengths.
+func (t *Track) Matches(other *Track) bool {
+	if t == nil || other == nil {
+		return false
+	}
+	if t.Info.ISRC != "" && other.Info.ISRC != "" {
+		return strings.EqualFold(t.Info.ISRC, other.Info.ISRC)
+	}
+	if t.Info.SourceName == other.Info.SourceName && t.Info.Identifier != "" {
+		if t.Info.Identifier == other.Info.Identifier {
+			return true
+		}
+	}
+	if t.Info.IsStream || other.Info.IsStream {
+		return false
+	}
+	diff := t.duration() - other.duration()
+	if diff < -matchLengthTolerance || diff > matchLengthTolerance {
+		return false
+	}
+	a, b := normalizeTitle(t.Info.Title), normalizeTitle(other.Info.Title)
+	if a == "" || b == "" {
+		return false
+	}
+	return a == b || strings.Contains(a, b) || strings.Contains(b, a)
+}
+
+// Lowercases the title and drops bracketed suffixes like "(Official Video)" and punctuation.
+func normalizeTitle(title string) string {
+	var sb strings.Builder
+	depth := 0
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r == '(' || r == '[':
+			depth++
+		case r == ')' || r == ']':
+			if depth > 0 {
+				depth--
+			}
+		case depth > 0:
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
@@ -0,0 +1,135 @@
+package lavago
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var rickInfo = TrackInfo{
+	Identifier: "dQw4w9WgXcQ",
+	Author:     "RickAstleyVEVO",
+	Title:      "Rick Astley - Never Gonna Give You Up",
+	CanSeek:    true,
+	Length:     212 * time.Second,
+	URL:        "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+	SourceName: "youtube",
+}
+
+func TestTrackInfoFixtures(t *testing.T) {
+	v4Info := rickInfo
+	v4Info.Artwork = "https://i.ytimg.com/vi/dQw4w9WgXcQ/maxresdefault.jpg"
+	stream := TrackInfo{
+		Identifier: "https://radio.example.com/live.mp3",
+		Author:     "Unknown artist",
+		Title:      "Unknown title",
+		Length:     math.MaxInt64,
+		IsStream:   true,
+		URL:        "https://radio.example.com/live.mp3",
+		SourceName: "http",
+	}
+	tests := []struct {
+		version, name string
+		want          TrackInfo
+	}{
+		{"v3", "loadTrack", rickInfo},
+		{"v4", "loadTrack", v4Info},
+		{"v3", "loadStream", stream},
+		{"v4", "loadStream", stream},
+	}
+	for _, tt := range tests {
+		sr := &SearchResult{}
+		if err := json.Unmarshal(fixture(t, tt.version, tt.name), sr); err != nil {
+			t.Fatalf("%s/%s: %v", tt.version, tt.name, err)
+		}
+		if len(sr.Tracks) != 1 {
+			t.Fatalf("%s/%s: got %d tracks", tt.version, tt.name, len(sr.Tracks))
+		}
+		if got := sr.Tracks[0].Info; got != tt.want {
+			t.Errorf("%s/%s:\ngot  %+v\nwant %+v", tt.version, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeTrackFixtures(t *testing.T) {
+	info := TrackInfo{}
+	if err := json.Unmarshal(fixture(t, "v3", "decodeTrack"), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info != rickInfo {
+		t.Errorf("v3: got %+v", info)
+	}
+	track := &Track{}
+	if err := json.Unmarshal(fixture(t, "v4", "decodeTrack"), track); err != nil {
+		t.Fatal(err)
+	}
+	if track.Track == "" || track.Info.Identifier != rickInfo.Identifier || track.Info.Length != rickInfo.Length {
+		t.Errorf("v4: got %+v", track)
+	}
+}
+
+// Lengths and positions go back to Lavalink in milliseconds under the names it sends them with.
+func TestTrackInfoMarshalJSON(t *testing.T) {
+	info := rickInfo
+	info.IsStream = true
+	info.Position = 1500 * time.Millisecond
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["length"] != float64(212000) || fields["position"] != float64(1500) || fields["isStream"] != true {
+		t.Errorf("got %s", data)
+	}
+	back := TrackInfo{}
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back != info {
+		t.Errorf("round trip: got %+v, want %+v", back, info)
+	}
+}
+
+func TestResolveArtworkURL(t *testing.T) {
+	const artwork = "https://i1.sndcdn.com/artworks-000123456789-abcdef-t500x500.jpg"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("url") != "https://soundcloud.com/artist/song" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"version":1,"type":"rich","thumbnail_url":"` + artwork + `"}`))
+	}))
+	defer srv.Close()
+	defer func(u string) { soundCloudOEmbedURL = u }(soundCloudOEmbedURL)
+	soundCloudOEmbedURL = srv.URL
+
+	tests := []struct {
+		name string
+		info TrackInfo
+		want string
+	}{
+		{"youtube", rickInfo, "https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg"},
+		{"sent by lavalink", TrackInfo{SourceName: "soundcloud", Artwork: "https://example.com/a.jpg"}, "https://example.com/a.jpg"},
+		{"soundcloud", TrackInfo{SourceName: "soundcloud", URL: "https://soundcloud.com/artist/song"}, artwork},
+		{"soundcloud unknown", TrackInfo{SourceName: "soundcloud", URL: "https://soundcloud.com/artist/gone"}, ""},
+		{"http", TrackInfo{SourceName: "http", URL: "https://example.com/a.mp3"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&Track{Info: tt.info}).ResolveArtworkURL(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package lavago
+
+import "runtime/debug"
+
+const modulePath = "github.com/nemphi/lavago"
+
+// Version of lavago the program was built with, i.e. "v1.2.3", or "dev" when it can't be
+// determined, such as for builds from a local checkout.
+var Version = moduleVersion()
+
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	mod := &info.Main
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			mod = dep
+			break
+		}
+	}
+	if mod.Path != modulePath || mod.Version == "" || mod.Version == "(devel)" {
+		return "dev"
+	}
+	return mod.Version
+}
@@ -0,0 +1,316 @@
+package lavago
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Information about a voice handshake that was forwarded to Lavalink.
+type VoiceConnectedEvent struct {
+	// Guild's voice connection.
+	GuildID string
+	// Discord voice session.
+	SessionID string
+	// Discord voice server, i.e. "us-east123.discord.media:443".
+	Endpoint string
+	// Voice region derived from the endpoint, i.e. "us-east".
+	Region string
+}
+
+// Information about a player whose voice connection dropped, i.e. because Discord's voice server had an outage.
+type VoiceDisconnectedEvent struct {
+	Player *Player
+}
+
+// What a node does when Discord closes a player's voice connection.
+type VoiceRecovery byte
+
+const (
+	// Leave the player as is.
+	VoiceRecoveryNone VoiceRecovery = iota
+	// Request a new voice connection through `Node.ConnectVoice` and restart the track at its position.
+	VoiceRecoveryRejoin
+	// Destroy the player.
+	VoiceRecoveryDestroy
+)
+
+// Discord voice gateway close codes.
+const (
+	VoiceCloseSessionNoLongerValid = 4006
+	VoiceCloseSessionTimeout       = 4009
+	VoiceCloseServerNotFound       = 4011
+	VoiceCloseDisconnected         = 4014
+	VoiceCloseServerCrashed        = 4015
+)
+
+// Rejoins when the voice session was invalidated or the voice server went away. Disconnects
+// are left alone, Discord sends them for moves as well as kicks, which the bot's voice state
+// update tells apart, see `Config.DestroyOnKick` and `PlayerMovedEvent`.
+func DefaultVoiceRecovery(code int) VoiceRecovery {
+	switch code {
+	case VoiceCloseSessionNoLongerValid, VoiceCloseSessionTimeout, VoiceCloseServerNotFound, VoiceCloseServerCrashed:
+		return VoiceRecoveryRejoin
+	}
+	return VoiceRecoveryNone
+}
+
+// Information about the bot being moved to another voice channel.
+type PlayerMovedEvent struct {
+	// Player for which this event fired.
+	Player *Player
+	// Channel the bot was in.
+	From string
+	// Channel the bot is in now.
+	To string
+}
+
+// Information about the bot being disconnected from its voice channel.
+type PlayerKickedEvent struct {
+	// Player for which this event fired. Already destroyed if `Config.DestroyOnKick` is set.
+	Player *Player
+	// Channel the bot was in.
+	ChannelID string
+}
+
+// Both halves of a guild's Discord voice handshake. Discord sends them in either order and
+// repeats them, so Lavalink is only told once both are present and something changed.
+type voiceConnection struct {
+	guildID   string
+	sessionID string
+	token     string
+	endpoint  string
+	// Last values sent to Lavalink.
+	sent serverUpdatePayload
+	sync.Mutex
+}
+
+func (n *Node) voiceConnection(guildID string) *voiceConnection {
+	vcI, _ := n.voiceConns.LoadOrStore(guildID, &voiceConnection{guildID: guildID})
+	return vcI.(*voiceConnection)
+}
+
+// Forwards Discord's VOICE_STATE_UPDATE for the bot user.
+func (n *Node) OnVoiceStateUpdate(shardUserID, triggerUserID, guildID, sessionID string) {
+	if shardUserID != triggerUserID {
+		return
+	}
+	n.updateVoiceSession(guildID, sessionID)
+}
+
+// Like OnVoiceStateUpdate, but also detects the bot being moved to another channel
+// or disconnected (empty channelID) and updates or destroys the player accordingly.
+func (n *Node) OnVoiceStateUpdateChannel(shardUserID, triggerUserID, guildID, channelID, sessionID string) {
+	if shardUserID != triggerUserID {
+		return
+	}
+	p := n.GetPlayer(guildID)
+	if p == nil {
+		n.updateVoiceSession(guildID, sessionID)
+		return
+	}
+	p.Lock()
+	from := p.ChannelID
+	if channelID != "" {
+		p.ChannelID = channelID
+	}
+	p.Unlock()
+	switch {
+	case channelID == "" && p.StayConnected():
+		if n.PlayerKicked != nil {
+			n.PlayerKicked(PlayerKickedEvent{Player: p, ChannelID: from})
+		}
+		if err := n.rejoin(p); err != nil {
+			n.socketOnError(err)
+		}
+		return
+	case channelID == "":
+		if n.config().DestroyOnKick {
+			err := n.destroyPlayer(p)
+			if err != nil {
+				n.socketOnError(err)
+			}
+		}
+		if n.PlayerKicked != nil {
+			n.PlayerKicked(PlayerKickedEvent{Player: p, ChannelID: from})
+		}
+		return
+	case channelID != from:
+		if n.PlayerMoved != nil {
+			n.PlayerMoved(PlayerMovedEvent{Player: p, From: from, To: channelID})
+		}
+	}
+	n.updateVoiceSession(guildID, sessionID)
+}
+
+func (n *Node) updateVoiceSession(guildID, sessionID string) {
+	vc := n.voiceConnection(guildID)
+	vc.Lock()
+	vc.sessionID = sessionID
+	vc.Unlock()
+	n.flushVoice(vc)
+}
+
+// Forwards Discord's VOICE_SERVER_UPDATE.
+func (n *Node) OnVoiceServerUpdate(guildID, endpoint, token string) {
+	// A missing endpoint means Discord is still allocating a voice server.
+	if endpoint == "" {
+		return
+	}
+	vc := n.voiceConnection(guildID)
+	vc.Lock()
+	vc.endpoint = endpoint
+	vc.token = token
+	vc.Unlock()
+	n.flushVoice(vc)
+}
+
+// Sends the voice handshake to Lavalink if it's complete and differs from what was sent last.
+func (n *Node) flushVoice(vc *voiceConnection) {
+	vc.Lock()
+	if vc.sessionID == "" || vc.token == "" || vc.endpoint == "" {
+		vc.Unlock()
+		return
+	}
+	sp := serverUpdatePayload{
+		Op:        "voiceUpdate",
+		GuildID:   vc.guildID,
+		SessionID: vc.sessionID,
+		Event: voiceServerPayload{
+			Endpoint: vc.endpoint,
+			Token:    vc.token,
+		},
+	}
+	if sp == vc.sent {
+		vc.Unlock()
+		return
+	}
+	err := n.sendVoice(sp)
+	if err != nil {
+		vc.Unlock()
+		n.socketOnError(err)
+		return
+	}
+	vc.sent = sp
+	vc.Unlock()
+	n.resumeAfterVoice(sp.GuildID)
+	if n.VoiceConnected == nil {
+		return
+	}
+	n.VoiceConnected(VoiceConnectedEvent{
+		GuildID:   sp.GuildID,
+		SessionID: sp.SessionID,
+		Endpoint:  sp.Event.Endpoint,
+		Region:    voiceRegion(sp.Event.Endpoint),
+	})
+}
+
+// Sends the voice handshake with the voiceUpdate op, or on Lavalink v4, which removed it,
+// through the player update endpoint.
+func (n *Node) sendVoice(sp serverUpdatePayload) error {
+	msg, err := n.codec().EncodeVoice(sp.GuildID, RemoteVoiceState{
+		Token:     sp.Event.Token,
+		Endpoint:  sp.Event.Endpoint,
+		SessionID: sp.SessionID,
+	})
+	if err != nil {
+		return err
+	}
+	return n.send(msg)
+}
+
+// Derives the voice region from an endpoint like "us-east123.discord.media:443".
+func voiceRegion(endpoint string) string {
+	host := endpoint
+	if i := strings.IndexAny(host, ".:"); i >= 0 {
+		host = host[:i]
+	}
+	return strings.TrimRight(host, "0123456789")
+}
+
+// Applies the configured recovery after Discord closed a guild's voice connection.
+// Players that stay connected always rejoin.
+func (n *Node) recoverVoice(guildID string, code int) {
+	p := n.GetPlayer(guildID)
+	if p == nil {
+		return
+	}
+	recovery := VoiceRecoveryNone
+	if n.config().VoiceRecovery != nil {
+		recovery = n.config().VoiceRecovery(code)
+	}
+	if p.StayConnected() {
+		recovery = VoiceRecoveryRejoin
+	}
+	switch recovery {
+	case VoiceRecoveryRejoin:
+		err := n.rejoin(p)
+		if err != nil {
+			n.socketOnError(err)
+		}
+	case VoiceRecoveryDestroy:
+		err := n.Leave(guildID)
+		if err != nil {
+			n.socketOnError(err)
+		}
+	}
+}
+
+// Requests a fresh voice connection to the player's channel, restarting the current track
+// at its position once the new handshake reached Lavalink.
+func (n *Node) rejoin(p *Player) error {
+	if n.ConnectVoice == nil {
+		return errors.New("can't rejoin, ConnectVoice isn't set")
+	}
+	p.Lock()
+	if p.Track != nil && p.State != PlayerStateStopped {
+		p.resumeAfterVoice = &PlayArgs{
+			Track:       p.Track,
+			Volume:      p.Volume,
+			StartTime:   p.position(),
+			ShouldPause: p.State == PlayerStatePaused,
+		}
+	}
+	channelID := p.ChannelID
+	p.Unlock()
+	// Forget the last handshake so the new one is sent even if Discord reuses the session.
+	vc := n.voiceConnection(p.GuildID)
+	vc.Lock()
+	vc.sent = serverUpdatePayload{}
+	vc.Unlock()
+	return n.ConnectVoice(p.GuildID, channelID, n.config().SelfDeaf)
+}
+
+func (n *Node) resumeAfterVoice(guildID string) {
+	p := n.GetPlayer(guildID)
+	if p == nil {
+		return
+	}
+	p.Lock()
+	args := p.resumeAfterVoice
+	p.resumeAfterVoice = nil
+	p.Unlock()
+	if args == nil {
+		return
+	}
+	err := p.Play(*args)
+	if err != nil {
+		n.socketOnError(err)
+	}
+}
+
+// Keeps the player in its voice channel around the clock: when the voice connection closes or
+// someone disconnects the bot, it rejoins the last channel and restarts the current track
+// instead of being destroyed. Defaults to `Config.StayConnected`.
+func (p *Player) SetStayConnected(on bool) {
+	p.Lock()
+	p.stayConnected = on
+	p.Unlock()
+}
+
+// Whether the player rejoins its channel after being disconnected, see SetStayConnected.
+func (p *Player) StayConnected() bool {
+	p.RLock()
+	defer p.RUnlock()
+	return p.stayConnected
+}